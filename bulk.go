@@ -1,7 +1,6 @@
 package shopify
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
@@ -14,25 +13,25 @@ import (
 	"strings"
 	"time"
 
-	"github.com/gempages/go-helper/tracing"
 	"github.com/gempages/go-shopify-graphql-model/graph/model"
-	"github.com/getsentry/sentry-go"
-	jsoniter "github.com/json-iterator/go"
-	log "github.com/sirupsen/logrus"
-	"gopkg.in/guregu/null.v4"
 
 	"github.com/gempages/go-shopify-graphql/graphql"
+	"github.com/gempages/go-shopify-graphql/metrics"
 	"github.com/gempages/go-shopify-graphql/rand"
+	"github.com/gempages/go-shopify-graphql/tracer"
 	"github.com/gempages/go-shopify-graphql/utils"
 )
 
 const (
 	edgesFieldName = "Edges"
 	nodeFieldName  = "Node"
+	nodesFieldName = "Nodes"
 )
 
 type BulkOperationService interface {
 	BulkQuery(ctx context.Context, query string, v interface{}) error
+	BulkQueryEach(ctx context.Context, query string, v interface{}, fn func(item interface{}) error) error
+	BulkMutate(ctx context.Context, mutation string, inputs []any) ([]BulkMutationResult, error)
 
 	PostBulkQuery(ctx context.Context, query string) (*string, error)
 	GetCurrentBulkQuery(ctx context.Context) (*model.BulkOperation, error)
@@ -41,6 +40,9 @@ type BulkOperationService interface {
 	ShouldGetBulkQueryResultURL(ctx context.Context, id *string) (*string, error)
 	CancelRunningBulkQuery(ctx context.Context) error
 	GetBulkQueryResult(ctx context.Context, id graphql.ID) (*model.BulkOperation, error)
+
+	GetBulkOperation(ctx context.Context, id string) (*model.BulkOperation, error)
+	Wait(ctx context.Context, id string) (*model.BulkOperation, error)
 }
 
 type BulkOperationServiceOp struct {
@@ -57,16 +59,20 @@ type mutationBulkOperationRunQueryCancel struct {
 	BulkOperationCancelResult model.BulkOperationCancelPayload `graphql:"bulkOperationCancel(id: $id)" json:"bulkOperationCancel"`
 }
 
+// bulkOperationPollInterval is how often Wait checks the status of a bulk operation.
+var bulkOperationPollInterval = time.Second
+
 var gidRegex *regexp.Regexp
 
 func init() {
-	gidRegex = regexp.MustCompile(`^gid://shopify/(\w+)/\d+$`)
+	// Some GIDs have parameters, e.g. gid://shopify/InventoryLevel/1?inventory_item_id=2.
+	gidRegex = regexp.MustCompile(`^gid://shopify/(\w+)/\d+(?:\?.*)?$`)
 }
 
 func (s *BulkOperationServiceOp) PostBulkQuery(ctx context.Context, query string) (*string, error) {
 	m := mutationBulkOperationRunQuery{}
 	vars := map[string]interface{}{
-		"query": null.StringFrom(query),
+		"query": graphql.Var(query, "String!"),
 	}
 
 	err := s.client.gql.Mutate(ctx, &m, vars)
@@ -82,12 +88,19 @@ func (s *BulkOperationServiceOp) PostBulkQuery(ctx context.Context, query string
 }
 
 func (s *BulkOperationServiceOp) GetCurrentBulkQuery(ctx context.Context) (*model.BulkOperation, error) {
+	return s.getCurrentBulkOperation(ctx, model.BulkOperationTypeQuery)
+}
+
+// getCurrentBulkOperation returns the latest bulk operation of type opType run by the app.
+func (s *BulkOperationServiceOp) getCurrentBulkOperation(ctx context.Context, opType model.BulkOperationType) (*model.BulkOperation, error) {
 	var q struct {
 		CurrentBulkOperation struct {
 			model.BulkOperation
-		}
+		} `graphql:"currentBulkOperation(type: $type)"`
 	}
-	err := s.client.gql.Query(ctx, &q, nil)
+	err := s.client.gql.Query(ctx, &q, map[string]interface{}{
+		"type": opType,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("query: %w", err)
 	}
@@ -98,20 +111,27 @@ func (s *BulkOperationServiceOp) GetCurrentBulkQueryResultURL(ctx context.Contex
 	return s.ShouldGetBulkQueryResultURL(ctx, nil)
 }
 
+// ShouldGetBulkQueryResultURL waits for the bulk operation id, or the current bulk query if id is nil,
+// and returns the URL of its result. The URL is nil if the operation returned no objects.
 func (s *BulkOperationServiceOp) ShouldGetBulkQueryResultURL(ctx context.Context, id *string) (*string, error) {
-	q, err := s.GetCurrentBulkQuery(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("getting current bulk operation: %w", err)
-	}
-
-	if id != nil && q.ID != *id {
-		return nil, fmt.Errorf("bulk operation ID doesn't match, got=%v, want=%v", q.ID, id)
+	var (
+		q   *model.BulkOperation
+		err error
+	)
+	if id != nil {
+		q, err = s.Wait(ctx, *id)
+	} else {
+		q, err = s.WaitForCurrentBulkQuery(ctx, bulkOperationPollInterval)
 	}
-
-	q, err = s.WaitForCurrentBulkQuery(ctx, 1*time.Second)
 	if err != nil {
 		return nil, fmt.Errorf("waiting for current bulk operation: %w", err)
 	}
+	return bulkOperationResultURL(q)
+}
+
+// bulkOperationResultURL returns the URL of the result of the finished bulk operation q,
+// or nil if it returned no objects.
+func bulkOperationResultURL(q *model.BulkOperation) (*string, error) {
 	if q.Status != model.BulkOperationStatusCompleted {
 		return nil, fmt.Errorf("bulk operation didn't complete, status=%s, error_code=%s", q.Status, q.ErrorCode)
 	}
@@ -131,26 +151,87 @@ func (s *BulkOperationServiceOp) ShouldGetBulkQueryResultURL(ctx context.Context
 	return q.URL, nil
 }
 
+// GetBulkOperation returns the bulk operation with the ID id, whether it is the current one or not.
+func (s *BulkOperationServiceOp) GetBulkOperation(ctx context.Context, id string) (*model.BulkOperation, error) {
+	var q struct {
+		Node struct {
+			BulkOperation model.BulkOperation `graphql:"... on BulkOperation"`
+		} `graphql:"node(id: $id)"`
+	}
+	err := s.client.gql.Query(ctx, &q, map[string]interface{}{
+		"id": id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	if q.Node.BulkOperation.ID == "" {
+		return nil, fmt.Errorf("bulk operation %s not found", id)
+	}
+	return &q.Node.BulkOperation, nil
+}
+
+// Wait polls the bulk operation with the ID id until it is done, and returns it.
+// Unlike WaitForCurrentBulkQuery, it isn't affected by other bulk operations started in the meantime.
+func (s *BulkOperationServiceOp) Wait(ctx context.Context, id string) (*model.BulkOperation, error) {
+	q, err := s.GetBulkOperation(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get bulk operation: %w", err)
+	}
+
+	for isBulkOperationRunning(q) {
+		s.client.log.Debugf("Bulk operation %s is still %s...", id, q.Status)
+		err = s.sleep(ctx, bulkOperationPollInterval)
+		if err != nil {
+			return q, err
+		}
+
+		q, err = s.GetBulkOperation(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("get bulk operation continuously: %w", err)
+		}
+	}
+	s.client.log.Debugf("Bulk operation %s ready, latest status=%s", id, q.Status)
+
+	return q, nil
+}
+
+func isBulkOperationRunning(q *model.BulkOperation) bool {
+	return q.Status == model.BulkOperationStatusCreated || q.Status == model.BulkOperationStatusRunning ||
+		q.Status == model.BulkOperationStatusCanceling
+}
+
+// sleep is graphql.Sleep, reported as a span of the client's tracer.
+func (s *BulkOperationServiceOp) sleep(ctx context.Context, d time.Duration) error {
+	_, span := s.client.gql.Tracer().Start(ctx, "time.sleep", "interval")
+	err := graphql.Sleep(ctx, d)
+	span.Finish(err)
+	return err
+}
+
 func (s *BulkOperationServiceOp) WaitForCurrentBulkQuery(ctx context.Context, interval time.Duration) (*model.BulkOperation, error) {
-	q, err := s.GetCurrentBulkQuery(ctx)
+	return s.waitForCurrentBulkOperation(ctx, model.BulkOperationTypeQuery, interval)
+}
+
+// waitForCurrentBulkOperation polls the current bulk operation of type opType until it is done.
+func (s *BulkOperationServiceOp) waitForCurrentBulkOperation(ctx context.Context, opType model.BulkOperationType, interval time.Duration) (*model.BulkOperation, error) {
+	q, err := s.getCurrentBulkOperation(ctx, opType)
 	if err != nil {
 		return q, fmt.Errorf("get current bulk query: %w", err)
 	}
 
-	for q.Status == model.BulkOperationStatusCreated || q.Status == model.BulkOperationStatusRunning || q.Status == model.BulkOperationStatusCanceling {
-		log.Debugf("Bulk operation is still %s...", q.Status)
-		span := sentry.StartSpan(ctx, "time.sleep")
-		span.Description = "interval"
-		time.Sleep(interval)
-		tracing.FinishSpan(span, ctx.Err())
-		ctx = span.Context()
+	for isBulkOperationRunning(q) {
+		s.client.log.Debugf("Bulk operation is still %s...", q.Status)
+		err = s.sleep(ctx, interval)
+		if err != nil {
+			return q, err
+		}
 
-		q, err = s.GetCurrentBulkQuery(ctx)
+		q, err = s.getCurrentBulkOperation(ctx, opType)
 		if err != nil {
 			return q, fmt.Errorf("get current bulk query continously: %w", err)
 		}
 	}
-	log.Debugf("Bulk operation ready, latest status=%s", q.Status)
+	s.client.log.Debugf("Bulk operation ready, latest status=%s", q.Status)
 
 	return q, nil
 }
@@ -162,7 +243,7 @@ func (s *BulkOperationServiceOp) CancelRunningBulkQuery(ctx context.Context) err
 	}
 
 	if q.Status == model.BulkOperationStatusCreated || q.Status == model.BulkOperationStatusRunning {
-		log.Debugln("Canceling running operation")
+		s.client.log.Debugln("Canceling running operation")
 		operationID := q.ID
 
 		m := mutationBulkOperationRunQueryCancel{}
@@ -182,37 +263,61 @@ func (s *BulkOperationServiceOp) CancelRunningBulkQuery(ctx context.Context) err
 		if err != nil {
 			return err
 		}
-		for q.Status == model.BulkOperationStatusCreated || q.Status == model.BulkOperationStatusRunning || q.Status == model.BulkOperationStatusCanceling {
-			log.Tracef("Bulk operation still %s...", q.Status)
+		for isBulkOperationRunning(q) {
+			s.client.log.Tracef("Bulk operation still %s...", q.Status)
+			err = s.sleep(ctx, bulkOperationPollInterval)
+			if err != nil {
+				return err
+			}
 			q, err = s.GetCurrentBulkQuery(ctx)
 			if err != nil {
 				return fmt.Errorf("get current bulk query: %w", err)
 			}
 		}
-		log.Debugln("Bulk operation cancelled")
+		s.client.log.Debugln("Bulk operation cancelled")
 	}
 
 	return nil
 }
 
 func (s *BulkOperationServiceOp) BulkQuery(ctx context.Context, query string, out interface{}) error {
+	return s.bulkQuery(ctx, query, func(resultFile string) error {
+		return parseBulkQueryResult(resultFile, query, out)
+	})
+}
+
+// BulkQueryEach runs query like BulkQuery, but reads the result one top-level object at a time
+// instead of holding it all in memory. v is a pointer to the type of the objects, e.g. &model.Product{}
+// or new(*model.Product), and fn is called with each object, of that type, with its nested connections.
+// Every object is decoded into a new value, so fn can keep it. An error returned by fn stops
+// the iteration and is returned.
+func (s *BulkOperationServiceOp) BulkQueryEach(ctx context.Context, query string, v interface{}, fn func(item interface{}) error) error {
+	if reflect.TypeOf(v).Kind() != reflect.Ptr {
+		return fmt.Errorf("the v arg is not a pointer")
+	}
+	return s.bulkQuery(ctx, query, func(resultFile string) error {
+		return eachBulkQueryResult(resultFile, query, reflect.TypeOf(v).Elem(), fn)
+	})
+}
+
+// bulkQuery runs query as a bulk operation and calls read with the path of the downloaded result,
+// if there is one.
+func (s *BulkOperationServiceOp) bulkQuery(ctx context.Context, query string, read func(resultFile string) error) error {
 	var (
 		id  *string
 		err error
 	)
 
-	// sentry tracing
-	span := sentry.StartSpan(ctx, "shopify_graphql.bulk_query")
-	span.Data = map[string]interface{}{
-		"GraphQL Query": query,
-	}
+	t := s.client.gql.Tracer()
+	operationName := utils.GetDescriptionFromQuery(query)
+	ctx, span := t.Start(tracer.ContextWithTracer(ctx, t), "shopify_graphql.bulk_query", operationName)
+	span.SetAttribute(tracer.AttributeOperationName, operationName)
+	span.SetAttribute(tracer.AttributeQuery, query)
 	defer func() {
-		tracing.FinishSpan(span, err)
+		span.Finish(err)
 	}()
-	ctx = span.Context()
-	// end sentry tracing
 
-	_, err = s.WaitForCurrentBulkQuery(ctx, time.Second)
+	_, err = s.WaitForCurrentBulkQuery(ctx, bulkOperationPollInterval)
 	if err != nil {
 		return fmt.Errorf("wait for current bulk query: %w", err)
 	}
@@ -226,7 +331,22 @@ func (s *BulkOperationServiceOp) BulkQuery(ctx context.Context, query string, ou
 		return fmt.Errorf("posted operation ID is nil")
 	}
 
-	url, err := s.ShouldGetBulkQueryResultURL(ctx, id)
+	bulkMetrics := metrics.BulkOperation{
+		ShopDomain:    s.client.gql.ShopDomain(),
+		OperationName: operationName,
+	}
+	defer func() {
+		bulkMetrics.Err = err
+		s.client.gql.Metrics().RecordBulkOperation(ctx, bulkMetrics)
+	}()
+
+	pollingStart := time.Now()
+	op, err := s.Wait(ctx, *id)
+	bulkMetrics.PollingDuration = time.Since(pollingStart)
+	if err != nil {
+		return fmt.Errorf("wait for bulk query: %w", err)
+	}
+	url, err := bulkOperationResultURL(op)
 	if err != nil {
 		return fmt.Errorf("get bulk query result URL: %w", err)
 	}
@@ -244,8 +364,11 @@ func (s *BulkOperationServiceOp) BulkQuery(ctx context.Context, query string, ou
 	if err != nil {
 		return fmt.Errorf("download file: %w", err)
 	}
+	if info, statErr := os.Stat(resultFile); statErr == nil {
+		bulkMetrics.ResultSize = info.Size()
+	}
 
-	err = parseBulkQueryResult(resultFile, out)
+	err = read(resultFile)
 	if err != nil {
 		return fmt.Errorf("parse bulk query result: %w", err)
 	}
@@ -255,6 +378,10 @@ func (s *BulkOperationServiceOp) BulkQuery(ctx context.Context, query string, ou
 
 // GetBulkQueryResult get current status of bulk query id
 func (s *BulkOperationServiceOp) GetBulkQueryResult(ctx context.Context, id graphql.ID) (*model.BulkOperation, error) {
+	if gid, ok := id.(string); ok && gid != "" {
+		return s.GetBulkOperation(ctx, gid)
+	}
+
 	q, err := s.GetCurrentBulkQuery(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("get current bulk query: %w", err)
@@ -313,7 +440,9 @@ func (b *bulkQueryBuilder) Build() string {
 	return q
 }
 
-func parseBulkQueryResult(resultFilePath string, out interface{}) error {
+// parseBulkQueryResult appends the top-level objects of the result file to the slice out points to.
+// query is used to attach nested connection nodes, it may be empty.
+func parseBulkQueryResult(resultFilePath string, query string, out interface{}) error {
 	if reflect.TypeOf(out).Kind() != reflect.Ptr {
 		return fmt.Errorf("the out arg is not a pointer")
 	}
@@ -326,236 +455,55 @@ func parseBulkQueryResult(resultFilePath string, out interface{}) error {
 
 	sliceItemType := outSlice.Type().Elem() // slice item type
 	sliceItemKind := sliceItemType.Kind()
-	itemType := sliceItemType // slice item underlying type
-	if sliceItemKind == reflect.Ptr {
-		itemType = itemType.Elem()
-	}
 
-	resultPath, err := os.Open(resultFilePath)
+	resultFile, err := os.Open(resultFilePath)
 	if err != nil {
 		return fmt.Errorf("open file: %w", err)
 	}
-	defer utils.CloseFile(resultPath)
-
-	reader := bufio.NewReader(resultPath)
-	json := jsoniter.ConfigFastest
-
-	connectionSink := make(map[string]interface{})
+	defer utils.CloseFile(resultFile)
 
+	d := newBulkResultDecoder(resultFile, query, sliceItemType)
 	for {
-		var line []byte
-		line, err = reader.ReadBytes('\n')
-		if err != nil {
-			break
+		item, err := d.Decode()
+		if errors.Is(err, io.EOF) {
+			return nil
 		}
-
-		parentIDNode := json.Get(line, "__parentId")
-		if parentIDNode.LastError() == nil {
-			parentID := parentIDNode.ToString()
-
-			gid := json.Get(line, "id")
-			if gid.LastError() != nil {
-				return fmt.Errorf("The connection type must query the `id` field")
-			}
-			edgeType, nodeType, connectionFieldName, err := concludeObjectType(gid.ToString())
-			if err != nil {
-				return err
-			}
-			node := reflect.New(nodeType).Interface()
-			err = json.Unmarshal(line, &node)
-			if err != nil {
-				return fmt.Errorf("unmarshalling: %w", err)
-			}
-			nodeVal := reflect.ValueOf(node).Elem()
-
-			var edge interface{}
-			var edgeVal reflect.Value
-			var nodeField reflect.Value
-			if edgeType.Kind() == reflect.Ptr {
-				edge = reflect.New(edgeType.Elem()).Interface()
-				nodeField = reflect.ValueOf(edge).Elem().FieldByName(nodeFieldName)
-				edgeVal = reflect.ValueOf(edge)
-			} else {
-				edge = reflect.New(edgeType).Interface()
-
-				if reflect.ValueOf(edge).Kind() == reflect.Ptr {
-					nodeField = reflect.ValueOf(edge).Elem().FieldByName(nodeFieldName)
-				} else {
-					nodeField = reflect.ValueOf(edge).FieldByName(nodeFieldName)
-				}
-
-				edgeVal = reflect.ValueOf(edge).Elem()
-			}
-
-			if !nodeField.IsValid() {
-				return fmt.Errorf("Edge in the '%s' doesn't have the Node field", connectionFieldName)
-			}
-			nodeField.Set(nodeVal)
-
-			var edgesSlice reflect.Value
-			var edges map[string]interface{}
-			if val, ok := connectionSink[parentID]; ok {
-				var ok2 bool
-				if edges, ok2 = val.(map[string]interface{}); !ok2 {
-					return fmt.Errorf("The connection sink for parent ID '%s' is not a map", parentID)
-				}
-			} else {
-				edges = make(map[string]interface{})
-			}
-
-			if val, ok := edges[connectionFieldName]; ok {
-				edgesSlice = reflect.ValueOf(val)
-			} else {
-				edgesSliceCap := 50
-				edgesSlice = reflect.MakeSlice(reflect.SliceOf(edgeType), 0, edgesSliceCap)
-			}
-
-			edgesSlice = reflect.Append(edgesSlice, edgeVal)
-
-			edges[connectionFieldName] = edgesSlice.Interface()
-			connectionSink[parentID] = edges
-
-			continue
-		}
-
-		item := reflect.New(itemType).Interface()
-		err = json.Unmarshal(line, &item)
 		if err != nil {
-			return fmt.Errorf("unmarshalling: %w", err)
+			return err
 		}
-		itemVal := reflect.ValueOf(item)
 
 		if sliceItemKind == reflect.Ptr {
-			outSlice.Set(reflect.Append(outSlice, itemVal))
+			outSlice.Set(reflect.Append(outSlice, item))
 		} else {
-			outSlice.Set(reflect.Append(outSlice, itemVal.Elem()))
+			outSlice.Set(reflect.Append(outSlice, item.Elem()))
 		}
 	}
-
-	if len(connectionSink) > 0 {
-		err := attachNestedConnections(connectionSink, outSlice)
-		if err != nil {
-			return fmt.Errorf("error processing nested connections: %w", err)
-		}
-	}
-
-	// check if ReadBytes returned an error different from EOF
-	if err != nil && !errors.Is(err, io.EOF) {
-		return fmt.Errorf("reading the result file: %w", err)
-	}
-
-	return nil
 }
 
-func attachNestedConnections(connectionSink map[string]interface{}, outSlice reflect.Value) error {
-	for i := 0; i < outSlice.Len(); i++ {
-		parent := outSlice.Index(i)
-		if parent.Kind() == reflect.Ptr {
-			parent = parent.Elem()
-		}
-
-		nodeField := parent.FieldByName("Node")
-		if nodeField != (reflect.Value{}) {
-			if nodeField.Kind() == reflect.Ptr {
-				parent = nodeField.Elem()
-			} else if nodeField.Kind() == reflect.Interface {
-				parent = nodeField.Elem().Elem()
-			} else {
-				parent = nodeField
-			}
-		}
+// eachBulkQueryResult calls fn with each top-level object of the result file in turn, as a value of itemType.
+func eachBulkQueryResult(resultFilePath string, query string, itemType reflect.Type, fn func(item interface{}) error) error {
+	resultFile, err := os.Open(resultFilePath)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer utils.CloseFile(resultFile)
 
-		parentIDField := parent.FieldByName("ID")
-		if parentIDField == (reflect.Value{}) {
-			return fmt.Errorf("No ID field on the first level")
-		}
-		if parentIDField.Kind() == reflect.Ptr {
-			parentIDField = parentIDField.Elem()
+	d := newBulkResultDecoder(resultFile, query, itemType)
+	for {
+		item, err := d.Decode()
+		if errors.Is(err, io.EOF) {
+			return nil
 		}
-
-		var parentID string
-		var ok bool
-		if parentID, ok = parentIDField.Interface().(string); !ok {
-			return fmt.Errorf("ID field on the first level is not a string")
+		if err != nil {
+			return err
 		}
 
-		var connection interface{}
-		if connection, ok = connectionSink[parentID]; !ok {
-			continue
+		if itemType.Kind() != reflect.Ptr {
+			item = item.Elem()
 		}
-
-		edgeMap := reflect.ValueOf(connection)
-		iter := edgeMap.MapRange()
-		for iter.Next() {
-			connectionName := iter.Key()
-			connectionField := parent.FieldByName(connectionName.String())
-			if !connectionField.IsValid() {
-				return fmt.Errorf("Connection '%s' is not defined on the parent type %s", connectionName.String(), parent.Type().String())
-			}
-
-			var connectionValue reflect.Value
-			var edgesField reflect.Value
-			if connectionField.Kind() == reflect.Ptr {
-				connectionValue = reflect.ValueOf(reflect.New(connectionField.Type().Elem()).Interface())
-				edgesField = connectionValue.Elem().FieldByName(edgesFieldName)
-			} else {
-				connectionValue = reflect.ValueOf(reflect.New(connectionField.Type()).Interface())
-				edgesField = connectionValue.Elem().FieldByName(edgesFieldName)
-			}
-
-			if !edgesField.IsValid() {
-				return fmt.Errorf("Connection %s in the '%s' doesn't have the Edges field", connectionName.String(), parent.Type().String())
-			}
-
-			edges := reflect.ValueOf(iter.Value().Interface())
-			edgesField.Set(edges)
-
-			connectionField.Set(connectionValue)
-
-			err := attachNestedConnections(connectionSink, iter.Value().Elem())
-			if err != nil {
-				return fmt.Errorf("error attacing a nested connection: %w", err)
-			}
+		err = fn(item.Interface())
+		if err != nil {
+			return err
 		}
 	}
-
-	return nil
-}
-
-func concludeObjectType(gid string) (reflect.Type, reflect.Type, string, error) {
-	submatches := gidRegex.FindStringSubmatch(gid)
-	if len(submatches) != 2 {
-		return reflect.TypeOf(nil), reflect.TypeOf(nil), "", fmt.Errorf("malformed gid=`%s`", gid)
-	}
-	resource := submatches[1]
-	switch resource {
-	case "LineItem":
-		return reflect.TypeOf(model.LineItemEdge{}), reflect.TypeOf(&model.LineItem{}), fmt.Sprintf("%ss", resource), nil
-	case "FulfillmentOrderLineItem":
-		return reflect.TypeOf(model.FulfillmentOrderLineItemEdge{}), reflect.TypeOf(&model.FulfillmentOrderLineItem{}), "LineItems", nil
-	case "FulfillmentOrder":
-		return reflect.TypeOf(model.FulfillmentOrderEdge{}), reflect.TypeOf(&model.FulfillmentOrder{}), fmt.Sprintf("%ss", resource), nil
-	case "MediaImage":
-		return reflect.TypeOf(model.MediaEdge{}), reflect.TypeOf(&model.MediaImage{}), "Media", nil
-	case "Metafield":
-		return reflect.TypeOf(model.MetafieldEdge{}), reflect.TypeOf(&model.Metafield{}), fmt.Sprintf("%ss", resource), nil
-	case "Order":
-		return reflect.TypeOf(model.OrderEdge{}), reflect.TypeOf(&model.Order{}), fmt.Sprintf("%ss", resource), nil
-	case "Product":
-		return reflect.TypeOf(model.ProductEdge{}), reflect.TypeOf(&model.Product{}), fmt.Sprintf("%ss", resource), nil
-	case "ProductVariant":
-		return reflect.TypeOf(model.ProductVariantEdge{}), reflect.TypeOf(&model.ProductVariant{}), "Variants", nil
-	case "Collection":
-		return reflect.TypeOf(model.CollectionEdge{}), reflect.TypeOf(&model.Collection{}), "Collections", nil
-	case "ProductImage":
-		return reflect.TypeOf(model.ImageEdge{}), reflect.TypeOf(&model.Image{}), "Images", nil
-	case "Video":
-		return reflect.TypeOf(model.MediaEdge{}), reflect.TypeOf(&model.Video{}), "Media", nil
-	case "Model3d":
-		return reflect.TypeOf(model.MediaEdge{}), reflect.TypeOf(&model.Model3d{}), "Media", nil
-	case "ExternalVideo":
-		return reflect.TypeOf(model.MediaEdge{}), reflect.TypeOf(&model.ExternalVideo{}), "Media", nil
-	default:
-		return reflect.TypeOf(nil), reflect.TypeOf(nil), "", fmt.Errorf("`%s` not implemented type", resource)
-	}
 }
@@ -0,0 +1,109 @@
+package shopify
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/gempages/go-shopify-graphql-model/graph/model"
+)
+
+// BulkConnection tells how the nested connection nodes of a bulk query result are attached to their parent.
+type BulkConnection struct {
+	// EdgeType is the type of the elements of the connection's Edges field, e.g. model.ProductVariantEdge.
+	EdgeType reflect.Type
+	// NodeType is the type the node is unmarshalled to, which is set to the edge's Node field, e.g. *model.ProductVariant.
+	NodeType reflect.Type
+	// FieldName is the name of the connection field on the parent struct, e.g. "Variants".
+	FieldName string
+}
+
+type bulkConnectionKey struct {
+	resource       string
+	parentResource string
+}
+
+// bulkConnections maps the GID resource of nodes, and optionally of their parent, to a BulkConnection.
+// See RegisterBulkConnection.
+var bulkConnections sync.Map
+
+func init() {
+	RegisterBulkConnection("Collection", "", model.CollectionEdge{}, &model.Collection{}, "Collections")
+	RegisterBulkConnection("Customer", "", model.CustomerEdge{}, &model.Customer{}, "Customers")
+	RegisterBulkConnection("DiscountRedeemCode", "", model.DiscountRedeemCodeEdge{}, &model.DiscountRedeemCode{}, "Codes")
+	RegisterBulkConnection("ExternalVideo", "", model.MediaEdge{}, &model.ExternalVideo{}, "Media")
+	RegisterBulkConnection("FulfillmentOrder", "", model.FulfillmentOrderEdge{}, &model.FulfillmentOrder{}, "FulfillmentOrders")
+	RegisterBulkConnection("FulfillmentOrderLineItem", "", model.FulfillmentOrderLineItemEdge{}, &model.FulfillmentOrderLineItem{}, "LineItems")
+	RegisterBulkConnection("InventoryLevel", "", model.InventoryLevelEdge{}, &model.InventoryLevel{}, "InventoryLevels")
+	RegisterBulkConnection("LineItem", "", model.LineItemEdge{}, &model.LineItem{}, "LineItems")
+	RegisterBulkConnection("MediaImage", "", model.MediaEdge{}, &model.MediaImage{}, "Media")
+	RegisterBulkConnection("Metafield", "", model.MetafieldEdge{}, &model.Metafield{}, "Metafields")
+	RegisterBulkConnection("Model3d", "", model.MediaEdge{}, &model.Model3d{}, "Media")
+	RegisterBulkConnection("Order", "", model.OrderEdge{}, &model.Order{}, "Orders")
+	RegisterBulkConnection("Product", "", model.ProductEdge{}, &model.Product{}, "Products")
+	RegisterBulkConnection("ProductImage", "", model.ImageEdge{}, &model.Image{}, "Images")
+	RegisterBulkConnection("ProductVariant", "", model.ProductVariantEdge{}, &model.ProductVariant{}, "Variants")
+	RegisterBulkConnection("Video", "", model.MediaEdge{}, &model.Video{}, "Media")
+}
+
+// RegisterBulkConnection makes the nodes of a bulk query result whose GID resource is resource,
+// e.g. "ProductVariant" for gid://shopify/ProductVariant/1, attached to their parent's fieldName connection,
+// as edges of the type of edge with nodes of the type of node. With a parentResource, the mapping only
+// applies under parents of that GID resource and takes precedence over the one registered with "".
+// For example, RegisterBulkConnection("Metafield", "ProductVariant", MyMetafieldEdge{}, &MyMetafield{}, "Metafields").
+// Registrations are used when the fields selected by the query don't tell the connection a node belongs to,
+// and to pick the type of the nodes of connections with an interface Node, e.g. the resource can also
+// be the "__typename" of nodes without id. It is meant to be called from init functions, and panics if the edge type has no Node field the node can be set to.
+func RegisterBulkConnection(resource, parentResource string, edge, node interface{}, fieldName string) {
+	conn := BulkConnection{
+		EdgeType:  reflect.TypeOf(edge),
+		NodeType:  reflect.TypeOf(node),
+		FieldName: fieldName,
+	}
+	edgeType := conn.EdgeType
+	if edgeType.Kind() == reflect.Ptr {
+		edgeType = edgeType.Elem()
+	}
+	nodeField, ok := edgeType.FieldByName(nodeFieldName)
+	if !ok {
+		panic(fmt.Sprintf("shopify: edge type %s doesn't have the Node field", conn.EdgeType))
+	}
+	if !conn.NodeType.AssignableTo(nodeField.Type) {
+		panic(fmt.Sprintf("shopify: node type %s can't be set to the Node field of %s", conn.NodeType, conn.EdgeType))
+	}
+	bulkConnections.Store(bulkConnectionKey{resource: resource, parentResource: parentResource}, conn)
+}
+
+// lookupBulkConnection returns the connection of the nodes with GID gid under the parent with GID parentGID.
+func lookupBulkConnection(gid, parentGID string) (BulkConnection, error) {
+	resource, ok := gidResource(gid)
+	if !ok {
+		return BulkConnection{}, fmt.Errorf("malformed gid=`%s`", gid)
+	}
+	parentResource, _ := gidResource(parentGID)
+	if conn, ok := lookupBulkConnectionResource(resource, parentResource); ok {
+		return conn, nil
+	}
+	return BulkConnection{}, fmt.Errorf("`%s` not implemented type, register it with RegisterBulkConnection", resource)
+}
+
+// lookupBulkConnectionResource returns the connection registered for resource under parentResource,
+// or for any parent.
+func lookupBulkConnectionResource(resource, parentResource string) (BulkConnection, bool) {
+	if conn, ok := bulkConnections.Load(bulkConnectionKey{resource: resource, parentResource: parentResource}); ok {
+		return conn.(BulkConnection), true
+	}
+	if conn, ok := bulkConnections.Load(bulkConnectionKey{resource: resource}); ok {
+		return conn.(BulkConnection), true
+	}
+	return BulkConnection{}, false
+}
+
+// gidResource returns the resource of gid, e.g. "Product" for gid://shopify/Product/1.
+func gidResource(gid string) (string, bool) {
+	submatches := gidRegex.FindStringSubmatch(gid)
+	if len(submatches) != 2 {
+		return "", false
+	}
+	return submatches[1], true
+}
@@ -0,0 +1,286 @@
+package shopify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gempages/go-shopify-graphql-model/graph/model"
+
+	"github.com/gempages/go-shopify-graphql/graphql"
+	"github.com/gempages/go-shopify-graphql/metrics"
+	"github.com/gempages/go-shopify-graphql/rand"
+	"github.com/gempages/go-shopify-graphql/tracer"
+	"github.com/gempages/go-shopify-graphql/utils"
+)
+
+const (
+	bulkMutationVariablesFilename = "bulk_mutation_variables.jsonl"
+	bulkMutationVariablesMimeType = "text/jsonl"
+	// stagedUploadPathParameter is the parameter of the staged upload target holding the path
+	// to pass to bulkOperationRunMutation.
+	stagedUploadPathParameter = "key"
+)
+
+type mutationBulkOperationRunMutation struct {
+	BulkOperationRunMutationResult model.BulkOperationRunMutationPayload `graphql:"bulkOperationRunMutation(mutation: $mutation, stagedUploadPath: $stagedUploadPath)" json:"bulkOperationRunMutation"`
+}
+
+// BulkMutationResult is the outcome of running the bulk mutation with one of its inputs.
+type BulkMutationResult struct {
+	// Index is the index of the input in the slice passed to BulkMutate.
+	Index int
+	// Data is the data returned by the mutation for the input, e.g. {"productSet":{"product":{...},"userErrors":[]}}.
+	Data json.RawMessage
+	// UserErrors are the userErrors of the mutation payload.
+	UserErrors []BulkMutationUserError
+	// Errors are the GraphQL errors returned for the input.
+	Errors graphql.Errors
+}
+
+// BulkMutationUserError is an error returned in the userErrors of a mutation payload.
+type BulkMutationUserError = MutationUserError
+
+// Failed reports whether the mutation failed for the input.
+func (r *BulkMutationResult) Failed() bool {
+	return len(r.UserErrors) > 0 || len(r.Errors) > 0
+}
+
+// bulkMutationResultLine is a line of the result file of a bulk mutation.
+type bulkMutationResultLine struct {
+	Data       json.RawMessage `json:"data"`
+	Errors     graphql.Errors  `json:"errors"`
+	LineNumber *int            `json:"__lineNumber"`
+}
+
+// BulkMutate runs mutation once per input with bulkOperationRunMutation, inputs being the variables
+// of each run, e.g. map[string]any{"input": model.ProductSetInput{...}}. It waits for the bulk operation
+// to complete and returns one result per input, in the order of inputs.
+func (s *BulkOperationServiceOp) BulkMutate(ctx context.Context, mutation string, inputs []any) ([]BulkMutationResult, error) {
+	var err error
+
+	t := s.client.gql.Tracer()
+	operationName := utils.GetDescriptionFromQuery(mutation)
+	ctx, span := t.Start(tracer.ContextWithTracer(ctx, t), "shopify_graphql.bulk_mutation", operationName)
+	span.SetAttribute(tracer.AttributeOperationName, operationName)
+	span.SetAttribute(tracer.AttributeQuery, mutation)
+	defer func() {
+		span.Finish(err)
+	}()
+
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	var variables bytes.Buffer
+	err = writeBulkMutationVariables(&variables, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("write variables: %w", err)
+	}
+
+	_, err = s.waitForCurrentBulkOperation(ctx, model.BulkOperationTypeMutation, bulkOperationPollInterval)
+	if err != nil {
+		return nil, fmt.Errorf("wait for current bulk mutation: %w", err)
+	}
+
+	stagedUploadPath, err := s.uploadBulkMutationVariables(ctx, &variables)
+	if err != nil {
+		return nil, fmt.Errorf("upload variables: %w", err)
+	}
+
+	m := mutationBulkOperationRunMutation{}
+	vars := map[string]interface{}{
+		"mutation":         graphql.Var(mutation, "String!"),
+		"stagedUploadPath": graphql.Var(stagedUploadPath, "String!"),
+	}
+	err = s.client.gql.Mutate(ctx, &m, vars)
+	if err != nil {
+		return nil, fmt.Errorf("run bulk mutation: %w", err)
+	}
+	if len(m.BulkOperationRunMutationResult.UserErrors) > 0 {
+		userErrors, _ := json.MarshalIndent(m.BulkOperationRunMutationResult.UserErrors, "", "    ")
+		err = fmt.Errorf("error running bulk mutation: %s", userErrors)
+		return nil, err
+	}
+	if m.BulkOperationRunMutationResult.BulkOperation == nil {
+		err = fmt.Errorf("started operation is nil")
+		return nil, err
+	}
+	id := m.BulkOperationRunMutationResult.BulkOperation.ID
+
+	bulkMetrics := metrics.BulkOperation{
+		ShopDomain:    s.client.gql.ShopDomain(),
+		OperationName: operationName,
+	}
+	defer func() {
+		bulkMetrics.Err = err
+		s.client.gql.Metrics().RecordBulkOperation(ctx, bulkMetrics)
+	}()
+
+	pollingStart := time.Now()
+	q, err := s.Wait(ctx, id)
+	bulkMetrics.PollingDuration = time.Since(pollingStart)
+	if err != nil {
+		return nil, fmt.Errorf("wait for bulk mutation: %w", err)
+	}
+	if q.Status != model.BulkOperationStatusCompleted {
+		err = fmt.Errorf("bulk operation didn't complete, status=%s, error_code=%s", q.Status, q.ErrorCode)
+		return nil, err
+	}
+
+	results := make([]BulkMutationResult, len(inputs))
+	for i := range results {
+		results[i].Index = i
+	}
+	if q.URL == nil || *q.URL == "" {
+		return results, nil
+	}
+
+	resultFile := filepath.Join(os.TempDir(), fmt.Sprintf("%s%s", rand.String(10), ".jsonl"))
+	// Clean up to avoid storage build up
+	defer os.Remove(resultFile)
+	err = utils.DownloadFile(ctx, resultFile, *q.URL)
+	if err != nil {
+		return nil, fmt.Errorf("download file: %w", err)
+	}
+	if info, statErr := os.Stat(resultFile); statErr == nil {
+		bulkMetrics.ResultSize = info.Size()
+	}
+
+	f, err := os.Open(resultFile)
+	if err != nil {
+		return nil, fmt.Errorf("open result file: %w", err)
+	}
+	defer utils.CloseFile(f)
+	err = parseBulkMutationResult(f, results)
+	if err != nil {
+		return nil, fmt.Errorf("parse bulk mutation result: %w", err)
+	}
+
+	return results, nil
+}
+
+// writeBulkMutationVariables writes the variables of every input as a line of JSONL to w.
+func writeBulkMutationVariables(w io.Writer, inputs []any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for i, input := range inputs {
+		err := enc.Encode(input)
+		if err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// uploadBulkMutationVariables uploads the JSONL variables to a staged upload target and returns its path.
+func (s *BulkOperationServiceOp) uploadBulkMutationVariables(ctx context.Context, variables *bytes.Buffer) (string, error) {
+	m := mutationStagedUploadsCreate{}
+	method := model.StagedUploadHTTPMethodTypePost
+	err := s.client.gql.Mutate(ctx, &m, map[string]interface{}{
+		"input": []model.StagedUploadInput{
+			{
+				Filename:   bulkMutationVariablesFilename,
+				HTTPMethod: &method,
+				MimeType:   bulkMutationVariablesMimeType,
+				Resource:   model.StagedUploadTargetGenerateUploadResourceBulkMutationVariables,
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("staged uploads create: %w", err)
+	}
+	if len(m.StagedUploadsCreateResult.UserErrors) > 0 {
+		return "", fmt.Errorf("%+v", m.StagedUploadsCreateResult.UserErrors)
+	}
+	if len(m.StagedUploadsCreateResult.StagedTargets) == 0 || m.StagedUploadsCreateResult.StagedTargets[0].URL == nil {
+		return "", fmt.Errorf("no staged upload target")
+	}
+	target := &m.StagedUploadsCreateResult.StagedTargets[0]
+
+	var stagedUploadPath string
+	for _, param := range target.Parameters {
+		if param.Name == stagedUploadPathParameter {
+			stagedUploadPath = param.Value
+		}
+	}
+	if stagedUploadPath == "" {
+		return "", fmt.Errorf("staged upload target has no %q parameter", stagedUploadPathParameter)
+	}
+
+	multiForm, err := createMultipartFormWithFile(variables, bulkMutationVariablesFilename, target)
+	if err != nil {
+		return "", fmt.Errorf("create multipart form: %w", err)
+	}
+	err = performHTTPPostWithHeaders(ctx, *target.URL, multiForm.data, map[string]string{
+		"Content-Type": multiForm.contentType,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return stagedUploadPath, nil
+}
+
+// parseBulkMutationResult reads the result file of a bulk mutation and fills results, indexed by input.
+// Lines are matched to inputs by their "__lineNumber", or by their position when it is missing.
+func parseBulkMutationResult(r io.Reader, results []BulkMutationResult) error {
+	reader := bufio.NewReader(r)
+	for position := 0; ; position++ {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var l bulkMutationResultLine
+			if jsonErr := json.Unmarshal(line, &l); jsonErr != nil {
+				return fmt.Errorf("line %d: %w", position, jsonErr)
+			}
+			index := position
+			if l.LineNumber != nil {
+				index = *l.LineNumber
+			}
+			if index < 0 || index >= len(results) {
+				return fmt.Errorf("line %d: input index %d out of range", position, index)
+			}
+			result := &results[index]
+			result.Data = l.Data
+			result.Errors = l.Errors
+			userErrs, userErrsErr := bulkMutationUserErrors(l.Data)
+			if userErrsErr != nil {
+				return fmt.Errorf("line %d: %w", position, userErrsErr)
+			}
+			result.UserErrors = userErrs
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// bulkMutationUserErrors collects the userErrors of the payloads in data.
+func bulkMutationUserErrors(data json.RawMessage) ([]BulkMutationUserError, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	var payloads map[string]*struct {
+		UserErrors []BulkMutationUserError `json:"userErrors"`
+	}
+	err := json.Unmarshal(data, &payloads)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal data: %w", err)
+	}
+	var userErrors []BulkMutationUserError
+	for _, payload := range payloads {
+		if payload != nil {
+			userErrors = append(userErrors, payload.UserErrors...)
+		}
+	}
+	return userErrors, nil
+}
@@ -0,0 +1,116 @@
+package shopify
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteBulkMutationVariables(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeBulkMutationVariables(&buf, []any{
+		map[string]any{"input": map[string]any{"title": "Sweet new snowboard"}},
+		map[string]any{"input": map[string]any{"title": "Winter <hat>"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"input":{"title":"Sweet new snowboard"}}
+{"input":{"title":"Winter <hat>"}}
+`
+	if buf.String() != expected {
+		t.Errorf("expected (%v), got (%v)", expected, buf.String())
+	}
+
+	err = writeBulkMutationVariables(&buf, []any{func() {}})
+	if err == nil {
+		t.Error("expected an error for an input that can't be marshaled")
+	}
+}
+
+func TestParseBulkMutationResult(t *testing.T) {
+	// Lines aren't guaranteed to be in input order, __lineNumber maps them back.
+	result := `{"data":{"productCreate":{"product":{"id":"gid://shopify/Product/2"},"userErrors":[]}},"__lineNumber":1}
+{"data":{"productCreate":{"product":null,"userErrors":[{"field":["input","title"],"message":"Title can't be blank","code":"BLANK"}]}},"__lineNumber":0}
+{"errors":[{"message":"Internal error"}],"__lineNumber":2}
+`
+	results := []BulkMutationResult{{Index: 0}, {Index: 1}, {Index: 2}, {Index: 3}}
+	err := parseBulkMutationResult(strings.NewReader(result), results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedUserErrors := []BulkMutationUserError{{Field: []string{"input", "title"}, Message: "Title can't be blank", Code: "BLANK"}}
+	if !reflect.DeepEqual(results[0].UserErrors, expectedUserErrors) {
+		t.Errorf("expected (%v), got (%v)", expectedUserErrors, results[0].UserErrors)
+	}
+	if !results[0].Failed() {
+		t.Error("expected input 0 to have failed")
+	}
+
+	if results[1].Failed() {
+		t.Errorf("expected input 1 to have succeeded, got (%+v)", results[1])
+	}
+	expectedData := `{"productCreate":{"product":{"id":"gid://shopify/Product/2"},"userErrors":[]}}`
+	if string(results[1].Data) != expectedData {
+		t.Errorf("expected (%v), got (%v)", expectedData, string(results[1].Data))
+	}
+
+	if len(results[2].Errors) != 1 || results[2].Errors[0].Message != "Internal error" {
+		t.Errorf("expected (%v), got (%v)", "Internal error", results[2].Errors)
+	}
+
+	if results[3].Data != nil || results[3].Failed() {
+		t.Errorf("expected input 3 to have no result, got (%+v)", results[3])
+	}
+}
+
+func TestParseBulkMutationResultWithoutLineNumber(t *testing.T) {
+	result := `{"data":{"productCreate":{"userErrors":[]}}}
+{"data":{"productCreate":{"userErrors":[{"field":null,"message":"Invalid","code":null}]}}}`
+	results := make([]BulkMutationResult, 2)
+	err := parseBulkMutationResult(strings.NewReader(result), results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Failed() {
+		t.Errorf("expected input 0 to have succeeded, got (%+v)", results[0])
+	}
+	if len(results[1].UserErrors) != 1 || results[1].UserErrors[0].Message != "Invalid" {
+		t.Errorf("expected (%v), got (%v)", "Invalid", results[1].UserErrors)
+	}
+
+	err = parseBulkMutationResult(strings.NewReader(`{"data":null,"__lineNumber":5}`), results)
+	if err == nil {
+		t.Error("expected an error for a line number out of range")
+	}
+}
+
+// failOnceReader fails the first read with err, and returns io.EOF afterward.
+type failOnceReader struct {
+	err    error
+	failed bool
+}
+
+func (r *failOnceReader) Read([]byte) (int, error) {
+	if r.failed {
+		return 0, io.EOF
+	}
+	r.failed = true
+	return 0, r.err
+}
+
+func TestParseBulkMutationResultReadError(t *testing.T) {
+	errBroken := errors.New("connection reset")
+	// The last line is cut by the read error, without a trailing newline.
+	r := io.MultiReader(strings.NewReader(`{"data":{"productCreate":{"userErrors":[]}}}
+{"data":{"productCreate":{"userErrors":[]}}}`), &failOnceReader{err: errBroken})
+	results := make([]BulkMutationResult, 2)
+	err := parseBulkMutationResult(r, results)
+	if !errors.Is(err, errBroken) {
+		t.Errorf("expected (%v), got (%v)", errBroken, err)
+	}
+}
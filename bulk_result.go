@@ -0,0 +1,472 @@
+package shopify
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// bulkResultDecoder reads the JSONL result of a bulk query one top-level object at a time.
+// Shopify writes the nested connection nodes of an object, identified by their "__parentId",
+// right after it, so an object is complete once the next top-level line is read.
+type bulkResultDecoder struct {
+	reader   *bufio.Reader
+	itemType reflect.Type
+	// selection is the selection of the top-level objects in the query, nil if unknown.
+	selection *bulkSelection
+	// next is the top-level line read ahead while collecting the nested nodes of the previous object.
+	next []byte
+}
+
+// newBulkResultDecoder returns a decoder of objects of type itemType, or of the type it points to.
+func newBulkResultDecoder(r io.Reader, query string, itemType reflect.Type) *bulkResultDecoder {
+	d := &bulkResultDecoder{
+		reader:   bufio.NewReader(r),
+		itemType: indirectType(itemType),
+	}
+	if query != "" {
+		// Without the selection, connections are found with the registry and the Go types only.
+		d.selection, _ = parseBulkSelection(query)
+	}
+	return d
+}
+
+// Decode returns a pointer to the next top-level object, with its nested connections attached.
+// It returns io.EOF once all objects are read.
+func (d *bulkResultDecoder) Decode() (reflect.Value, error) {
+	json := jsoniter.ConfigFastest
+
+	line := d.next
+	d.next = nil
+	if line == nil {
+		var err error
+		line, err = d.readLine()
+		if err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	if json.Get(line, "__parentId").LastError() == nil {
+		return reflect.Value{}, fmt.Errorf("nested connection node without a top-level object before it")
+	}
+
+	root := &bulkNode{value: reflect.New(d.itemType), selection: d.selection}
+	err := json.Unmarshal(line, root.value.Interface())
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("unmarshalling: %w", err)
+	}
+
+	// Nodes are only referenced by their ID, nodes without one can't have nested connections.
+	nodes := make(map[string]*bulkNode)
+	indexBulkNode(nodes, root, line)
+	for {
+		line, err = d.readLine()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		parentIDNode := json.Get(line, "__parentId")
+		if parentIDNode.LastError() != nil {
+			d.next = line
+			break
+		}
+		parentID := parentIDNode.ToString()
+		parent, ok := nodes[parentID]
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("parent '%s' of a nested connection node not found, the parent must query the `id` field", parentID)
+		}
+		child, err := parent.addChild(line, parentID)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		indexBulkNode(nodes, child, line)
+	}
+
+	err = root.attach()
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("error processing nested connections: %w", err)
+	}
+	return root.value, nil
+}
+
+// readLine returns the next non-empty line, or io.EOF.
+func (d *bulkResultDecoder) readLine() ([]byte, error) {
+	for {
+		line, err := d.reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				err = fmt.Errorf("reading the result file: %w", err)
+			}
+			return nil, err
+		}
+	}
+}
+
+// bulkNode is an object of a bulk query result, with the nested connection nodes read so far.
+type bulkNode struct {
+	// value is a pointer to the unmarshalled object.
+	value reflect.Value
+	// selection is the selection of the object in the query, nil if unknown.
+	selection   *bulkSelection
+	connections []*bulkNodeConnection
+}
+
+// bulkNodeConnection holds the nodes of one of the connections of a bulkNode, in the order of the result.
+type bulkNodeConnection struct {
+	field bulkConnectionField
+	nodes []*bulkNode
+}
+
+func indexBulkNode(nodes map[string]*bulkNode, node *bulkNode, line []byte) {
+	id := jsoniter.ConfigFastest.Get(line, "id")
+	if id.ValueType() == jsoniter.StringValue {
+		nodes[id.ToString()] = node
+	}
+}
+
+// addChild unmarshals the nested connection node on line and adds it to the connection of n it belongs to.
+func (n *bulkNode) addChild(line []byte, parentID string) (*bulkNode, error) {
+	json := jsoniter.ConfigFastest
+
+	var gid, typename string
+	if id := json.Get(line, "id"); id.ValueType() == jsoniter.StringValue {
+		gid = id.ToString()
+	}
+	if t := json.Get(line, "__typename"); t.ValueType() == jsoniter.StringValue {
+		typename = t.ToString()
+	}
+	var registered *BulkConnection
+	if gid != "" {
+		if conn, err := lookupBulkConnection(gid, parentID); err == nil {
+			registered = &conn
+		}
+	}
+	if registered == nil && typename != "" {
+		parentResource, _ := gidResource(parentID)
+		if conn, ok := lookupBulkConnectionResource(typename, parentResource); ok {
+			registered = &conn
+		}
+	}
+
+	field, selection, err := n.findConnection(json.Get(line).Keys(), registered)
+	if err != nil {
+		return nil, err
+	}
+	nodeType := field.nodeType
+	if nodeType.Kind() == reflect.Interface {
+		if registered == nil || !registered.NodeType.AssignableTo(nodeType) {
+			return nil, fmt.Errorf("can't tell the type of the nodes of connection '%s' on %s, query their `id` or `__typename` and register it with RegisterBulkConnection",
+				field.name, n.value.Type().Elem())
+		}
+		nodeType = registered.NodeType
+	}
+
+	child := &bulkNode{value: reflect.New(indirectType(nodeType)), selection: selection}
+	err = json.Unmarshal(line, child.value.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling: %w", err)
+	}
+
+	var conn *bulkNodeConnection
+	for _, c := range n.connections {
+		if c.field.name == field.name {
+			conn = c
+			break
+		}
+	}
+	if conn == nil {
+		conn = &bulkNodeConnection{field: field}
+		n.connections = append(n.connections, conn)
+	}
+	conn.nodes = append(conn.nodes, child)
+	return child, nil
+}
+
+// findConnection returns the connection field of n a nested node with the JSON keys keys belongs to,
+// and the node's selection if known. The connections selected by the query are matched against keys first,
+// preferring the ones selecting exactly keys over the ones selecting more fields,
+// then the connection registered for the node's type is used, and otherwise the node is matched against
+// the connection fields of n's type.
+func (n *bulkNode) findConnection(keys []string, registered *BulkConnection) (bulkConnectionField, *bulkSelection, error) {
+	parentType := n.value.Type().Elem()
+
+	if n.selection != nil {
+		var (
+			candidates []*bulkSelection
+			fields     []bulkConnectionField
+		)
+		for _, s := range n.selection.connections() {
+			if !s.nodeSelection().hasKeys(keys) {
+				continue
+			}
+			field, ok := connectionFieldByKey(parentType, s.key)
+			if !ok {
+				return bulkConnectionField{}, nil, fmt.Errorf("Connection '%s' is not defined on the parent type %s", s.key, parentType)
+			}
+			candidates = append(candidates, s)
+			fields = append(fields, field)
+		}
+		// A connection selecting more fields also has all the keys of a node of one selecting fewer,
+		// so connections selecting exactly the node's keys come first.
+		if len(candidates) > 1 {
+			var (
+				exact       []*bulkSelection
+				exactFields []bulkConnectionField
+			)
+			for i, c := range candidates {
+				if c.nodeSelection().hasExactKeys(keys) {
+					exact = append(exact, c)
+					exactFields = append(exactFields, fields[i])
+				}
+			}
+			if len(exact) > 0 {
+				candidates, fields = exact, exactFields
+			}
+		}
+		// Connections selecting the same fields can only be told apart by the type of their nodes.
+		if len(candidates) > 1 && registered != nil {
+			var compatible []int
+			for i, field := range fields {
+				if field.accepts(registered) {
+					compatible = append(compatible, i)
+				}
+			}
+			if len(compatible) == 1 {
+				i := compatible[0]
+				candidates, fields = candidates[i:i+1], fields[i:i+1]
+			}
+		}
+		switch len(candidates) {
+		case 1:
+			return fields[0], candidates[0].nodeSelection(), nil
+		case 0:
+			// The query may not be parsed right, fall back to the types.
+		default:
+			keys := make([]string, len(candidates))
+			for i, c := range candidates {
+				keys[i] = c.key
+			}
+			return bulkConnectionField{}, nil, fmt.Errorf("can't tell which of the connections %s on %s a node belongs to, query different fields on them",
+				strings.Join(keys, ", "), parentType)
+		}
+	}
+
+	if registered != nil {
+		if field, ok := connectionFieldByName(parentType, registered.FieldName); ok {
+			return field, nil, nil
+		}
+	}
+
+	var candidates []bulkConnectionField
+	for _, field := range connectionFields(parentType) {
+		if registered != nil {
+			if field.accepts(registered) {
+				candidates = append(candidates, field)
+			}
+			continue
+		}
+		if field.nodeType.Kind() != reflect.Interface && structHasKeys(indirectType(field.nodeType), keys) {
+			candidates = append(candidates, field)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil, nil
+	}
+	if registered != nil {
+		return bulkConnectionField{}, nil, fmt.Errorf("Connection '%s' is not defined on the parent type %s", registered.FieldName, parentType)
+	}
+	return bulkConnectionField{}, nil, fmt.Errorf("can't tell which connection of %s a node with the fields %s belongs to, register it with RegisterBulkConnection",
+		parentType, strings.Join(keys, ", "))
+}
+
+// attach sets the connection fields of n to its nested nodes, after attaching theirs.
+func (n *bulkNode) attach() error {
+	obj := n.value.Elem()
+	for _, c := range n.connections {
+		elemType := c.field.nodeType
+		listFieldName := nodesFieldName
+		if c.field.edgeType != nil {
+			elemType = c.field.edgeType
+			listFieldName = edgesFieldName
+		}
+
+		items := reflect.MakeSlice(reflect.SliceOf(elemType), 0, len(c.nodes))
+		for _, child := range c.nodes {
+			err := child.attach()
+			if err != nil {
+				return err
+			}
+			node := child.value
+			if !node.Type().AssignableTo(c.field.nodeType) {
+				node = node.Elem()
+			}
+			if c.field.edgeType == nil {
+				items = reflect.Append(items, node)
+				continue
+			}
+			edge := reflect.New(indirectType(c.field.edgeType))
+			edge.Elem().FieldByName(nodeFieldName).Set(node)
+			if c.field.edgeType.Kind() != reflect.Ptr {
+				edge = edge.Elem()
+			}
+			items = reflect.Append(items, edge)
+		}
+
+		connection := fieldByIndexAlloc(obj, c.field.index)
+		if connection.Kind() == reflect.Ptr {
+			if connection.IsNil() {
+				connection.Set(reflect.New(connection.Type().Elem()))
+			}
+			connection = connection.Elem()
+		}
+		connection.FieldByName(listFieldName).Set(items)
+	}
+	return nil
+}
+
+// bulkConnectionField is a connection field of a struct, which has either an Edges field of edges with
+// a Node field, or a Nodes field.
+type bulkConnectionField struct {
+	name     string
+	jsonName string
+	index    []int
+	// edgeType is the type of the elements of the Edges field, or nil for a connection with a Nodes field.
+	edgeType reflect.Type
+	// nodeType is the type of the Node field of the edges, or of the elements of the Nodes field.
+	nodeType reflect.Type
+}
+
+// accepts reports whether the nodes registered with conn can be set to the connection.
+func (f bulkConnectionField) accepts(conn *BulkConnection) bool {
+	if f.edgeType != nil && f.edgeType == conn.EdgeType {
+		return true
+	}
+	return conn.NodeType.AssignableTo(f.nodeType) || indirectType(conn.NodeType) == indirectType(f.nodeType)
+}
+
+// connectionFields returns the connection fields of t, including the promoted ones.
+func connectionFields(t reflect.Type) []bulkConnectionField {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []bulkConnectionField
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		if field, ok := newConnectionField(f); ok {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func newConnectionField(f reflect.StructField) (bulkConnectionField, bool) {
+	field := bulkConnectionField{name: f.Name, jsonName: jsonFieldName(f), index: f.Index}
+	t := indirectType(f.Type)
+	if t.Kind() != reflect.Struct {
+		return field, false
+	}
+	if edges, ok := t.FieldByName(edgesFieldName); ok && edges.Type.Kind() == reflect.Slice {
+		edgeType := edges.Type.Elem()
+		if indirectType(edgeType).Kind() != reflect.Struct {
+			return field, false
+		}
+		node, ok := indirectType(edgeType).FieldByName(nodeFieldName)
+		if !ok {
+			return field, false
+		}
+		field.edgeType = edgeType
+		field.nodeType = node.Type
+		return field, true
+	}
+	if nodes, ok := t.FieldByName(nodesFieldName); ok && nodes.Type.Kind() == reflect.Slice {
+		field.nodeType = nodes.Type.Elem()
+		return field, true
+	}
+	return field, false
+}
+
+// connectionFieldByKey returns the connection field of t the JSON key key is decoded to.
+func connectionFieldByKey(t reflect.Type, key string) (bulkConnectionField, bool) {
+	for _, field := range connectionFields(t) {
+		if field.jsonName == key || (field.jsonName == "" && strings.EqualFold(field.name, key)) {
+			return field, true
+		}
+	}
+	return bulkConnectionField{}, false
+}
+
+func connectionFieldByName(t reflect.Type, name string) (bulkConnectionField, bool) {
+	for _, field := range connectionFields(t) {
+		if field.name == name {
+			return field, true
+		}
+	}
+	return bulkConnectionField{}, false
+}
+
+// structHasKeys reports whether all of keys are decoded to fields of t, ignoring keys starting with "__".
+func structHasKeys(t reflect.Type, keys []string) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	names := make(map[string]bool)
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		name := jsonFieldName(f)
+		if name == "" {
+			name = f.Name
+		}
+		names[strings.ToLower(name)] = true
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "__") && !names[strings.ToLower(key)] {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonFieldName returns the name of f in its json tag, if any.
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// fieldByIndexAlloc returns the field of v at index, allocating the nil embedded pointers on the way.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
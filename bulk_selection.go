@@ -0,0 +1,148 @@
+package shopify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// maxFragmentDepth bounds how deeply fragment spreads are expanded, so cyclic fragments can't loop forever.
+const maxFragmentDepth = 10
+
+// bulkSelection is a field selected by a bulk query. Shopify writes each node of a nested connection
+// on its own line with only its parent's ID, so the selection is used to tell which connection it belongs to.
+type bulkSelection struct {
+	// name is the name of the field, and key its alias if it has one, or its name.
+	name string
+	key  string
+	// fields are the selected fields, with the fields of fragments inlined.
+	fields []*bulkSelection
+}
+
+// parseBulkSelection returns the selection of the nodes of the top-level connection of a bulk query.
+func parseBulkSelection(query string) (*bulkSelection, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("no operation")
+	}
+
+	fields, err := newBulkSelectionFields(doc, doc.Operations[0].SelectionSet, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no top-level field")
+	}
+	nodes := fields[0].nodeSelection()
+	if nodes == nil {
+		return nil, fmt.Errorf("top-level field %s is not a connection", fields[0].name)
+	}
+	return nodes, nil
+}
+
+// newBulkSelectionFields returns the fields of set, inlining the fields of inline fragments and fragment spreads.
+// depth is the number of fragment spreads set is nested in.
+func newBulkSelectionFields(doc *ast.QueryDocument, set ast.SelectionSet, depth int) ([]*bulkSelection, error) {
+	var fields []*bulkSelection
+	for _, selection := range set {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			sub, err := newBulkSelectionFields(doc, selection.SelectionSet, depth)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, &bulkSelection{name: selection.Name, key: selection.Alias, fields: sub})
+		case *ast.InlineFragment:
+			sub, err := newBulkSelectionFields(doc, selection.SelectionSet, depth)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, sub...)
+		case *ast.FragmentSpread:
+			if depth >= maxFragmentDepth {
+				return nil, fmt.Errorf("fragment %s: nested more than %d levels deep", selection.Name, maxFragmentDepth)
+			}
+			fragment := doc.Fragments.ForName(selection.Name)
+			if fragment == nil {
+				return nil, fmt.Errorf("fragment %s: not defined", selection.Name)
+			}
+			sub, err := newBulkSelectionFields(doc, fragment.SelectionSet, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, sub...)
+		}
+	}
+	return fields, nil
+}
+
+// nodeSelection returns the selection of the nodes of s, or nil if s isn't a connection.
+func (s *bulkSelection) nodeSelection() *bulkSelection {
+	for _, f := range s.fields {
+		switch f.name {
+		case "edges":
+			for _, ef := range f.fields {
+				if ef.name == "node" {
+					return ef
+				}
+			}
+		case "nodes":
+			return f
+		}
+	}
+	return nil
+}
+
+// connections returns the nested connections selected on s.
+func (s *bulkSelection) connections() []*bulkSelection {
+	var connections []*bulkSelection
+	for _, f := range s.fields {
+		if f.nodeSelection() != nil {
+			connections = append(connections, f)
+		}
+	}
+	return connections
+}
+
+// hasKeys reports whether all of keys are selected on s, ignoring nested connections which are written
+// on their own lines, and keys starting with "__".
+func (s *bulkSelection) hasKeys(keys []string) bool {
+	selected := s.selectedKeys()
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "__") && !selected[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasExactKeys reports whether keys are exactly the keys selected on s, ignoring nested connections
+// and keys starting with "__".
+func (s *bulkSelection) hasExactKeys(keys []string) bool {
+	if !s.hasKeys(keys) {
+		return false
+	}
+	matched := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "__") {
+			matched[key] = true
+		}
+	}
+	return len(matched) == len(s.selectedKeys())
+}
+
+// selectedKeys returns the keys selected on s, except nested connections and keys starting with "__".
+func (s *bulkSelection) selectedKeys() map[string]bool {
+	selected := make(map[string]bool, len(s.fields))
+	for _, f := range s.fields {
+		if f.nodeSelection() == nil && !strings.HasPrefix(f.key, "__") {
+			selected[f.key] = true
+		}
+	}
+	return selected
+}
@@ -0,0 +1,556 @@
+package shopify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gempages/go-shopify-graphql-model/graph/model"
+
+	graphqlclient "github.com/gempages/go-shopify-graphql/graph"
+)
+
+const productsWithVariantsResult = `{"id":"gid://shopify/Product/1","title":"Snowboard"}
+{"id":"gid://shopify/ProductVariant/11","title":"Small","__parentId":"gid://shopify/Product/1"}
+{"id":"gid://shopify/ProductVariant/12","title":"Large","__parentId":"gid://shopify/Product/1"}
+{"id":"gid://shopify/Product/2","title":"Hat"}
+{"id":"gid://shopify/Product/3","title":"Ski"}
+{"id":"gid://shopify/ProductVariant/31","title":"Default","__parentId":"gid://shopify/Product/3"}`
+
+func writeBulkResult(t *testing.T, result string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "result.jsonl")
+	err := os.WriteFile(path, []byte(result), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func variantTitles(p model.Product) []string {
+	if p.Variants == nil {
+		return nil
+	}
+	var titles []string
+	for _, edge := range p.Variants.Edges {
+		titles = append(titles, edge.Node.Title)
+	}
+	return titles
+}
+
+func TestParseBulkQueryResult(t *testing.T) {
+	path := writeBulkResult(t, productsWithVariantsResult)
+
+	var products []*model.Product
+	err := parseBulkQueryResult(path, "", &products)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 3 {
+		t.Fatalf("expected (%v), got (%v)", 3, len(products))
+	}
+	expected := [][]string{{"Small", "Large"}, nil, {"Default"}}
+	for i, p := range products {
+		got := variantTitles(*p)
+		if len(got) != len(expected[i]) {
+			t.Errorf("expected (%v), got (%v)", expected[i], got)
+			continue
+		}
+		for j := range got {
+			if got[j] != expected[i][j] {
+				t.Errorf("expected (%v), got (%v)", expected[i], got)
+			}
+		}
+	}
+}
+
+func TestEachBulkQueryResult(t *testing.T) {
+	path := writeBulkResult(t, productsWithVariantsResult)
+
+	var products []model.Product
+	err := eachBulkQueryResult(path, "", reflect.TypeOf(model.Product{}), func(item interface{}) error {
+		products = append(products, item.(model.Product))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Kept items must not be overwritten by the next ones.
+	var (
+		titles   []string
+		variants []int
+	)
+	for _, product := range products {
+		titles = append(titles, product.Title)
+		variants = append(variants, len(variantTitles(product)))
+	}
+	if len(titles) != 3 || titles[0] != "Snowboard" || titles[1] != "Hat" || titles[2] != "Ski" {
+		t.Errorf("expected (%v), got (%v)", []string{"Snowboard", "Hat", "Ski"}, titles)
+	}
+	// The variants of a product must not leak into the next one.
+	if len(variants) != 3 || variants[0] != 2 || variants[1] != 0 || variants[2] != 1 {
+		t.Errorf("expected (%v), got (%v)", []int{2, 0, 1}, variants)
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = eachBulkQueryResult(path, "", reflect.TypeOf(&model.Product{}), func(item interface{}) error {
+		calls++
+		productPtr := item.(*model.Product)
+		if productPtr.ID != "gid://shopify/Product/1" {
+			t.Errorf("expected (%v), got (%v)", "gid://shopify/Product/1", productPtr.ID)
+		}
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("expected (%v), got (%v)", errStop, err)
+	}
+	if calls != 1 {
+		t.Errorf("expected (%v), got (%v)", 1, calls)
+	}
+}
+
+func TestEachBulkQueryResultOrphanNode(t *testing.T) {
+	path := writeBulkResult(t, `{"id":"gid://shopify/ProductVariant/11","__parentId":"gid://shopify/Product/1"}`)
+
+	err := eachBulkQueryResult(path, "", reflect.TypeOf(model.Product{}), func(interface{}) error { return nil })
+	if err == nil {
+		t.Error("expected an error for a nested node without a top-level object")
+	}
+}
+
+type bulkTestVariant struct {
+	ID  string `json:"id"`
+	SKU string `json:"sku"`
+}
+
+type bulkTestVariantEdge struct {
+	Node bulkTestVariant `json:"node"`
+}
+
+type bulkTestProduct struct {
+	ID       string `json:"id"`
+	Variants struct {
+		Edges []bulkTestVariantEdge `json:"edges"`
+	} `json:"variants"`
+}
+
+type bulkTestInventoryItem struct {
+	ID              string                          `json:"id"`
+	InventoryLevels *model.InventoryLevelConnection `json:"inventoryLevels"`
+}
+
+func TestRegisterBulkConnection(t *testing.T) {
+	path := writeBulkResult(t, `{"id":"gid://shopify/Product/1"}
+{"id":"gid://shopify/ProductVariant/11","sku":"SNOW-S","__parentId":"gid://shopify/Product/1"}`)
+
+	// The types of the connection are taken from the parent struct.
+	var products []bulkTestProduct
+	err := parseBulkQueryResult(path, "", &products)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 1 || len(products[0].Variants.Edges) != 1 {
+		t.Fatalf("expected 1 product with 1 variant, got (%+v)", products)
+	}
+	if sku := products[0].Variants.Edges[0].Node.SKU; sku != "SNOW-S" {
+		t.Errorf("expected (%v), got (%v)", "SNOW-S", sku)
+	}
+
+	RegisterBulkConnection("ProductVariant", "Product", bulkTestVariantEdge{}, bulkTestVariant{}, "Variants")
+	defer bulkConnections.Delete(bulkConnectionKey{resource: "ProductVariant", parentResource: "Product"})
+
+	conn, err := lookupBulkConnection("gid://shopify/ProductVariant/11", "gid://shopify/Product/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.EdgeType != reflect.TypeOf(bulkTestVariantEdge{}) {
+		t.Errorf("expected (%v), got (%v)", reflect.TypeOf(bulkTestVariantEdge{}), conn.EdgeType)
+	}
+
+	// Other parents still use the default mapping.
+	conn, err = lookupBulkConnection("gid://shopify/ProductVariant/11", "gid://shopify/Order/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.EdgeType != reflect.TypeOf(model.ProductVariantEdge{}) {
+		t.Errorf("expected (%v), got (%v)", reflect.TypeOf(model.ProductVariantEdge{}), conn.EdgeType)
+	}
+}
+
+func TestBulkConnectionGIDWithParameters(t *testing.T) {
+	path := writeBulkResult(t, `{"id":"gid://shopify/InventoryItem/1"}
+{"id":"gid://shopify/InventoryLevel/7?inventory_item_id=1","__parentId":"gid://shopify/InventoryItem/1"}`)
+
+	var items []bulkTestInventoryItem
+	err := parseBulkQueryResult(path, "", &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].InventoryLevels == nil || len(items[0].InventoryLevels.Edges) != 1 {
+		t.Fatalf("expected 1 item with 1 inventory level, got (%+v)", items)
+	}
+
+	_, err = lookupBulkConnection("gid://shopify/CollectionRuleSet/1", "")
+	if err == nil {
+		t.Error("expected an error for an unregistered resource")
+	}
+}
+
+func TestRegisterBulkConnectionPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a node type that can't be set to the edge")
+		}
+	}()
+	RegisterBulkConnection("ProductVariant", "", model.ProductVariantEdge{}, &model.Product{}, "Variants")
+}
+
+const ordersBulkQuery = `
+{
+  orders(query: "created_at:>2024-01-01") {
+    edges {
+      node {
+        id
+        name
+        lineItems {
+          edges { node { id title quantity } }
+        }
+        nonFulfillableLineItems {
+          edges { node { id sku } }
+        }
+        metafields(namespace: "custom") {
+          edges { node { namespace key value } }
+        }
+        fulfillmentOrders {
+          edges {
+            node {
+              id
+              status
+              lineItems { edges { node { id remainingQuantity } } }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestParseBulkQueryResultNestedConnections(t *testing.T) {
+	var orders []model.Order
+	err := parseBulkQueryResult(filepath.Join("testdata", "bulk", "orders.jsonl"), ordersBulkQuery, &orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 {
+		t.Fatalf("expected (%v), got (%v)", 2, len(orders))
+	}
+
+	order := orders[0]
+	if order.LineItems == nil || len(order.LineItems.Edges) != 2 {
+		t.Fatalf("expected 2 line items, got (%+v)", order.LineItems)
+	}
+	if title := order.LineItems.Edges[1].Node.Title; title != "Wax" {
+		t.Errorf("expected (%v), got (%v)", "Wax", title)
+	}
+	// Both connections have LineItem nodes, they are told apart by the fields the query selects.
+	if order.NonFulfillableLineItems == nil || len(order.NonFulfillableLineItems.Edges) != 1 {
+		t.Fatalf("expected 1 non fulfillable line item, got (%+v)", order.NonFulfillableLineItems)
+	}
+	if sku := order.NonFulfillableLineItems.Edges[0].Node.Sku; sku == nil || *sku != "GIFT-CARD" {
+		t.Errorf("expected (%v), got (%v)", "GIFT-CARD", sku)
+	}
+	// Nodes without id are attached too.
+	if order.Metafields == nil || len(order.Metafields.Edges) != 1 || order.Metafields.Edges[0].Node.Key != "gift" {
+		t.Errorf("expected the gift metafield, got (%+v)", order.Metafields)
+	}
+	if order.FulfillmentOrders == nil || len(order.FulfillmentOrders.Edges) != 1 {
+		t.Fatalf("expected 1 fulfillment order, got (%+v)", order.FulfillmentOrders)
+	}
+	fulfillmentOrder := order.FulfillmentOrders.Edges[0].Node
+	if fulfillmentOrder.LineItems == nil || len(fulfillmentOrder.LineItems.Edges) != 2 {
+		t.Fatalf("expected 2 fulfillment order line items, got (%+v)", fulfillmentOrder.LineItems)
+	}
+	if q := fulfillmentOrder.LineItems.Edges[0].Node.RemainingQuantity; q != 2 {
+		t.Errorf("expected (%v), got (%v)", 2, q)
+	}
+
+	order = orders[1]
+	if order.LineItems != nil {
+		t.Errorf("expected no line items, got (%+v)", order.LineItems)
+	}
+	if order.FulfillmentOrders == nil || len(order.FulfillmentOrders.Edges) != 1 ||
+		order.FulfillmentOrders.Edges[0].Node.Status != model.FulfillmentOrderStatusClosed {
+		t.Errorf("expected 1 closed fulfillment order, got (%+v)", order.FulfillmentOrders)
+	}
+}
+
+const productsMediaBulkQuery = `
+query products {
+  products(query: "title:'{snow}'") {
+    edges {
+      node {
+        id
+        title
+        media {
+          edges {
+            node {
+              ... on MediaImage { id image { url } }
+              ... on Video { id sources { url } }
+              __typename
+            }
+          }
+        }
+        productVariants: variants {
+          edges { node { ...Variant } }
+        }
+      }
+    }
+  }
+}
+
+fragment Variant on ProductVariant {
+  id
+  title
+  metafields { edges { node { id key value } } }
+}`
+
+type bulkTestMediaProduct struct {
+	ID       string                          `json:"id"`
+	Title    string                          `json:"title"`
+	Media    *model.MediaConnection          `json:"media"`
+	Variants *model.ProductVariantConnection `json:"productVariants"`
+}
+
+// ordersOverlappingBulkQuery selects on nonFulfillableLineItems all the fields of lineItems and more.
+const ordersOverlappingBulkQuery = `
+{
+  orders {
+    edges {
+      node {
+        id
+        name
+        lineItems {
+          edges { node { id title } }
+        }
+        nonFulfillableLineItems {
+          edges { node { id title sku } }
+        }
+      }
+    }
+  }
+}`
+
+func TestParseBulkQueryResultOverlappingConnections(t *testing.T) {
+	var orders []model.Order
+	err := parseBulkQueryResult(filepath.Join("testdata", "bulk", "orders_overlapping.jsonl"), ordersOverlappingBulkQuery, &orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 {
+		t.Fatalf("expected (%v), got (%v)", 1, len(orders))
+	}
+	// Nodes with exactly the fields of lineItems belong to it, even though nonFulfillableLineItems selects them too.
+	order := orders[0]
+	if order.LineItems == nil || len(order.LineItems.Edges) != 2 {
+		t.Fatalf("expected 2 line items, got (%+v)", order.LineItems)
+	}
+	if title := order.LineItems.Edges[1].Node.Title; title != "Wax" {
+		t.Errorf("expected (%v), got (%v)", "Wax", title)
+	}
+	if order.NonFulfillableLineItems == nil || len(order.NonFulfillableLineItems.Edges) != 1 {
+		t.Fatalf("expected 1 non fulfillable line item, got (%+v)", order.NonFulfillableLineItems)
+	}
+	if sku := order.NonFulfillableLineItems.Edges[0].Node.Sku; sku == nil || *sku != "GIFT-CARD" {
+		t.Errorf("expected (%v), got (%v)", "GIFT-CARD", sku)
+	}
+}
+
+func TestParseBulkQueryResultMixedNodeTypes(t *testing.T) {
+	var products []bulkTestMediaProduct
+	err := parseBulkQueryResult(filepath.Join("testdata", "bulk", "products_media.jsonl"), productsMediaBulkQuery, &products)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 1 {
+		t.Fatalf("expected (%v), got (%v)", 1, len(products))
+	}
+	product := products[0]
+
+	if product.Media == nil || len(product.Media.Edges) != 3 {
+		t.Fatalf("expected 3 media, got (%+v)", product.Media)
+	}
+	image, ok := product.Media.Edges[0].Node.(*model.MediaImage)
+	if !ok || image.Image == nil || image.Image.URL != "https://cdn.shopify.com/snowboard.png" {
+		t.Errorf("expected a media image, got (%+v)", product.Media.Edges[0].Node)
+	}
+	video, ok := product.Media.Edges[1].Node.(*model.Video)
+	if !ok || len(video.Sources) != 1 {
+		t.Errorf("expected a video, got (%+v)", product.Media.Edges[1].Node)
+	}
+	// The type of nodes without id is taken from __typename.
+	if _, ok := product.Media.Edges[2].Node.(*model.MediaImage); !ok {
+		t.Errorf("expected a media image, got (%+v)", product.Media.Edges[2].Node)
+	}
+
+	if product.Variants == nil || len(product.Variants.Edges) != 1 {
+		t.Fatalf("expected 1 variant, got (%+v)", product.Variants)
+	}
+	variant := product.Variants.Edges[0].Node
+	if variant.Metafields == nil || len(variant.Metafields.Edges) != 1 || variant.Metafields.Edges[0].Node.Value != "wood" {
+		t.Errorf("expected the material metafield, got (%+v)", variant.Metafields)
+	}
+}
+
+func TestParseBulkQueryResultAmbiguousConnections(t *testing.T) {
+	path := writeBulkResult(t, `{"id":"gid://shopify/Order/1"}
+{"id":"gid://shopify/LineItem/11","__parentId":"gid://shopify/Order/1"}`)
+	query := `{ orders { edges { node {
+		id
+		lineItems { edges { node { id } } }
+		nonFulfillableLineItems { edges { node { id } } }
+	} } } }`
+
+	var orders []model.Order
+	err := parseBulkQueryResult(path, query, &orders)
+	if err == nil {
+		t.Error("expected an error for connections that can't be told apart")
+	}
+
+	// Without the query, the registered connection is used.
+	orders = nil
+	err = parseBulkQueryResult(path, "", &orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].LineItems == nil || len(orders[0].LineItems.Edges) != 1 {
+		t.Errorf("expected 1 order with 1 line item, got (%+v)", orders)
+	}
+}
+
+func TestParseBulkSelection(t *testing.T) {
+	s, err := parseBulkSelection(productsMediaBulkQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, c := range s.connections() {
+		keys = append(keys, c.key)
+	}
+	if !reflect.DeepEqual(keys, []string{"media", "productVariants"}) {
+		t.Errorf("expected (%v), got (%v)", []string{"media", "productVariants"}, keys)
+	}
+	media := s.connections()[0].nodeSelection()
+	if !media.hasKeys([]string{"id", "sources", "__parentId"}) {
+		t.Error("expected the media selection to have the video fields")
+	}
+	if media.hasKeys([]string{"id", "title"}) {
+		t.Error("expected the media selection not to have the title field")
+	}
+
+	_, err = parseBulkSelection(`{ shop { name } }`)
+	if err == nil {
+		t.Error("expected an error for a query without a top-level connection")
+	}
+
+	_, err = parseBulkSelection(`{ products { edges { node { ...a } } } } fragment a on Product { ...b } fragment b on Product { ...a }`)
+	if err == nil {
+		t.Error("expected an error for cyclic fragments")
+	}
+}
+
+func TestBulkQueryWaitsForItsOperation(t *testing.T) {
+	interval := bulkOperationPollInterval
+	bulkOperationPollInterval = time.Millisecond
+	defer func() { bulkOperationPollInterval = interval }()
+
+	result := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, productsWithVariantsResult)
+	}))
+	defer result.Close()
+
+	var polls int
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		var data string
+		switch {
+		case strings.Contains(body.Query, "bulkOperationRunQuery("):
+			data = `{"bulkOperationRunQuery":{"bulkOperation":{"id":"gid://shopify/BulkOperation/1"},"userErrors":[]}}`
+		case strings.Contains(body.Query, "currentBulkOperation("):
+			data = `{"currentBulkOperation":{"id":"gid://shopify/BulkOperation/0","status":"COMPLETED"}}`
+		case strings.Contains(body.Query, "node(id: $id)"):
+			if body.Variables["id"] != "gid://shopify/BulkOperation/1" {
+				t.Errorf("expected (%v), got (%v)", "gid://shopify/BulkOperation/1", body.Variables["id"])
+			}
+			polls++
+			status := "RUNNING"
+			if polls > 1 {
+				status = "COMPLETED"
+			}
+			data = fmt.Sprintf(`{"node":{"id":"gid://shopify/BulkOperation/1","status":%q,"objectCount":"6","url":%q}}`,
+				status, result.URL)
+		default:
+			t.Errorf("unexpected query %s", body.Query)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"data":` + data + `}`)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	})
+	c, err := New("example.myshopify.com", WithAccessToken("token"),
+		WithGraphQLOptions(graphqlclient.WithBaseTransport(base)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var products []*model.Product
+	err = c.BulkOperation.BulkQuery(context.Background(), `{ products { edges { node { id title variants { edges { node { id title } } } } } } }`, &products)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polls != 2 {
+		t.Errorf("expected (%v), got (%v)", 2, polls)
+	}
+	if len(products) != 3 {
+		t.Errorf("expected (%v), got (%v)", 3, len(products))
+	}
+}
+
+func TestGetBulkOperationNotFound(t *testing.T) {
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"data":{"node":null}}`)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	})
+	c, err := New("example.myshopify.com", WithAccessToken("token"),
+		WithGraphQLOptions(graphqlclient.WithBaseTransport(base)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.BulkOperation.GetBulkOperation(context.Background(), "gid://shopify/BulkOperation/1")
+	if err == nil {
+		t.Error("expected an error for a missing bulk operation")
+	}
+}
@@ -1,21 +1,54 @@
 package shopify
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 
 	graphqlclient "github.com/gempages/go-shopify-graphql/graph"
 	"github.com/gempages/go-shopify-graphql/graphql"
+	"github.com/gempages/go-shopify-graphql/tracer"
 
 	log "github.com/sirupsen/logrus"
 )
 
 const (
-	shopifyAPIVersion           = "default"
-	shopifyStoreFrontAPIVersion = "default"
+	shopifyAPIVersion           = APIVersionDefault
+	shopifyStoreFrontAPIVersion = APIVersionDefault
 )
 
+// ErrUnsupportedForAPI is returned by the services that don't exist on the API the client talks to,
+// e.g. Order on a Storefront API client.
+var ErrUnsupportedForAPI = errors.New("unsupported for API")
+
+// ErrMissingAuth is returned by New when none of WithAccessToken, WithPrivateAppAuth, WithStorefrontToken
+// or WithStorefrontPrivateToken is given.
+var ErrMissingAuth = errors.New("missing auth option")
+
+// API is the Shopify API a Client talks to.
+type API int
+
+const (
+	AdminAPI API = iota
+	StorefrontAPI
+)
+
+func (a API) String() string {
+	switch a {
+	case AdminAPI:
+		return "Admin"
+	case StorefrontAPI:
+		return "Storefront"
+	}
+	return fmt.Sprintf("API(%d)", int(a))
+}
+
 type Client struct {
-	gql *graphql.Client
+	gql     *graphql.Client
+	api     API
+	version APIVersion
+	log     log.Ext1FieldLogger
 
 	Product             ProductService
 	Variant             VariantService
@@ -57,109 +90,152 @@ func NewDefaultClient() (shopClient *Client) {
 	return
 }
 
+// New returns a new Shopify GRAPHQL client for shop, the shop's myshopify domain.
+// The API it talks to is chosen by the auth option: WithAccessToken and WithPrivateAppAuth for the Admin API,
+// WithStorefrontToken and WithStorefrontPrivateToken for the Storefront API. Every service is set,
+// the ones that don't exist on the chosen API fail with ErrUnsupportedForAPI.
+// It fails if an option is invalid, e.g. an unknown API version, or if no auth option is given.
+func New(shop string, opts ...Option) (*Client, error) {
+	o := &clientOptions{
+		api:     AdminAPI,
+		version: APIVersionDefault,
+		logger:  log.StandardLogger(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+	if !o.authenticated {
+		return nil, ErrMissingAuth
+	}
+	graphOpts := append([]graphqlclient.Option{graphqlclient.WithVersion(string(o.version))}, o.graphOpts...)
+	// WithGraphQLOptions can override the version of WithAPIVersion.
+	version := APIVersion(graphqlclient.Version(graphOpts...))
+	if err := version.Validate(); err != nil {
+		return nil, err
+	}
+	c := newClient(graphqlclient.NewClient(shop, graphOpts...), o.api, o.logger)
+	c.version = version
+	return c, nil
+}
+
 // NewClient returns a new Shopify Admin GRAPHQL client with
 // private app authenticated apiKey and password. The storeName parameter is the shop's myshopify domain
 func NewClient(apiKey string, password string, storeName string) *Client {
-	c := &Client{gql: newShopifyGraphQLClient(apiKey, password, storeName)}
-
-	c.Product = &ProductServiceOp{client: c}
-	c.Variant = &VariantServiceOp{client: c}
-	c.Inventory = &InventoryServiceOp{client: c}
-	c.Cart = &CartServiceOp{client: c}
-	c.Billing = &BillingServiceOp{client: c}
-	c.Collection = &CollectionServiceOp{client: c}
-	c.Order = &OrderServiceOp{client: c}
-	c.Fulfillment = &FulfillmentServiceOp{client: c}
-	c.Location = &LocationServiceOp{client: c}
-	c.Metafield = &MetafieldServiceOp{client: c}
-	c.MetafieldDefinition = &MetafieldDefinitionServiceOp{client: c}
-	c.BulkOperation = &BulkOperationServiceOp{client: c}
-	c.Webhook = &WebhookServiceOp{client: c}
-	c.File = &FileServiceOp{client: c}
-	c.App = &AppServiceOp{client: c}
-	c.Discount = &DiscountServiceOp{client: c}
-
-	return c
+	return newClient(newShopifyGraphQLClient(apiKey, password, storeName), AdminAPI, log.StandardLogger())
 }
 
 func newShopifyGraphQLClient(apiKey string, password string, storeName string) *graphql.Client {
 	opts := []graphqlclient.Option{
-		graphqlclient.WithVersion(shopifyAPIVersion),
+		graphqlclient.WithVersion(string(shopifyAPIVersion)),
 		graphqlclient.WithPrivateAppAuth(apiKey, password),
 	}
 	return graphqlclient.NewClient(storeName, opts...)
 }
 
+// APIVersion returns the API version the client was created with. APIVersionDefault means Shopify picks it,
+// or that it was set through lower level options
+func (c *Client) APIVersion() APIVersion {
+	return c.version
+}
+
+// API returns the Shopify API the client talks to
+func (c *Client) API() API {
+	return c.api
+}
+
 func (c *Client) GraphQLClient() *graphql.Client {
 	return c.gql
 }
 
+// RateLimitStatus returns the query cost and throttle status reported by Shopify for the last call,
+// or nil if no call has completed yet.
+func (c *Client) RateLimitStatus() *graphql.QueryCost {
+	return c.gql.RateLimitStatus()
+}
+
 func (c *Client) SetRetries(retryCount int) {
 	c.gql.SetRetries(retryCount)
 }
 
+// SetRetryPolicy replaces the policy deciding whether and when failed requests are retried
+func (c *Client) SetRetryPolicy(policy graphql.RetryPolicy) {
+	c.gql.SetRetryPolicy(policy)
+}
+
 // NewClientWithOpts returns a new Shopify GRAPHQL client with custom graphql options
 func NewClientWithOpts(storeName string, opts ...graphqlclient.Option) *Client {
-	c := &Client{gql: graphqlclient.NewClient(storeName, opts...)}
-
-	c.Product = &ProductServiceOp{client: c}
-	c.Variant = &VariantServiceOp{client: c}
-	c.Inventory = &InventoryServiceOp{client: c}
-	c.Cart = &CartServiceOp{client: c}
-	c.Billing = &BillingServiceOp{client: c}
-	c.Collection = &CollectionServiceOp{client: c}
-	c.Order = &OrderServiceOp{client: c}
-	c.Fulfillment = &FulfillmentServiceOp{client: c}
-	c.Location = &LocationServiceOp{client: c}
-	c.Metafield = &MetafieldServiceOp{client: c}
-	c.MetafieldDefinition = &MetafieldDefinitionServiceOp{client: c}
-	c.BulkOperation = &BulkOperationServiceOp{client: c}
-	c.Webhook = &WebhookServiceOp{client: c}
-	c.File = &FileServiceOp{client: c}
-	c.App = &AppServiceOp{client: c}
-	c.Discount = &DiscountServiceOp{client: c}
-
-	return c
+	return newClient(graphqlclient.NewClient(storeName, opts...), AdminAPI, log.StandardLogger())
 }
 
 // NewClientWithToken returns a new Shopify Admin GRAPHQL client with
 //
 //	authenticated domain and token
 func NewClientWithToken(apiKey string, storeName string) *Client {
-	c := &Client{gql: newShopifyGraphQLClientWithToken(apiKey, storeName)}
-
-	c.Product = &ProductServiceOp{client: c}
-	c.Variant = &VariantServiceOp{client: c}
-	// c.Inventory = &InventoryServiceOp{client: c}
-	c.Cart = &CartServiceOp{client: c}
-	c.Billing = &BillingServiceOp{client: c}
-	c.Collection = &CollectionServiceOp{client: c}
-	// c.Order = &OrderServiceOp{client: c}
-	// c.Fulfillment = &FulfillmentServiceOp{client: c}
-	// c.Location = &LocationServiceOp{client: c}
-	c.Metafield = &MetafieldServiceOp{client: c}
-	c.MetafieldDefinition = &MetafieldDefinitionServiceOp{client: c}
-	c.BulkOperation = &BulkOperationServiceOp{client: c}
-	c.Webhook = &WebhookServiceOp{client: c}
-	c.Discount = &DiscountServiceOp{client: c}
-
-	return c
+	return newClient(newShopifyGraphQLClientWithToken(apiKey, storeName), AdminAPI, log.StandardLogger())
 }
 
 // NewClientStoreFrontWithToken returns a new Shopify Storefront GRAPHQL client with
 // authenticated domain and token. The client can only use function for storefront
 func NewClientStoreFrontWithToken(apiKey string, storeName string) *Client {
-	c := &Client{gql: newShopifyStoreFrontGraphQLClientWithToken(apiKey, storeName)}
-	c.Cart = &CartServiceOp{client: c}
-	c.Product = &ProductServiceOp{client: c}
-	c.Collection = &CollectionServiceOp{client: c}
+	return newClient(newShopifyStoreFrontGraphQLClientWithToken(apiKey, storeName), StorefrontAPI, log.StandardLogger())
+}
 
+// newClient wires every service of the client. Services that don't exist on api are
+// wired to a GraphQL client failing every call with ErrUnsupportedForAPI.
+func newClient(gql *graphql.Client, api API, logger log.Ext1FieldLogger) *Client {
+	c := &Client{gql: gql, api: api, version: APIVersionDefault, log: logger}
+	c.setServices(c.forAPI)
 	return c
 }
 
+// setServices sets the services of c, each using the client returned by clientFor for its name and supported APIs.
+func (c *Client) setServices(clientFor func(service string, supported ...API) *Client) {
+	c.Product = &ProductServiceOp{client: clientFor("Product", AdminAPI, StorefrontAPI)}
+	c.Variant = &VariantServiceOp{client: clientFor("Variant", AdminAPI)}
+	c.Inventory = &InventoryServiceOp{client: clientFor("Inventory", AdminAPI)}
+	c.Cart = &CartServiceOp{client: clientFor("Cart", StorefrontAPI)}
+	c.Billing = &BillingServiceOp{client: clientFor("Billing", AdminAPI)}
+	c.Collection = &CollectionServiceOp{client: clientFor("Collection", AdminAPI, StorefrontAPI)}
+	c.Order = &OrderServiceOp{client: clientFor("Order", AdminAPI)}
+	c.Fulfillment = &FulfillmentServiceOp{client: clientFor("Fulfillment", AdminAPI)}
+	c.Location = &LocationServiceOp{client: clientFor("Location", AdminAPI)}
+	c.Metafield = &MetafieldServiceOp{client: clientFor("Metafield", AdminAPI)}
+	c.MetafieldDefinition = &MetafieldDefinitionServiceOp{client: clientFor("MetafieldDefinition", AdminAPI)}
+	c.BulkOperation = &BulkOperationServiceOp{client: clientFor("BulkOperation", AdminAPI)}
+	c.Webhook = &WebhookServiceOp{client: clientFor("Webhook", AdminAPI)}
+	c.File = &FileServiceOp{client: clientFor("File", AdminAPI)}
+	c.App = &AppServiceOp{client: clientFor("App", AdminAPI)}
+	c.Discount = &DiscountServiceOp{client: clientFor("Discount", AdminAPI)}
+}
+
+// forAPI returns c if it talks to one of the supported APIs, otherwise a client
+// whose calls fail with ErrUnsupportedForAPI without reaching Shopify. The services of that client
+// use it too, so services calling each other, e.g. Order using BulkOperation, fail the same way.
+func (c *Client) forAPI(service string, supported ...API) *Client {
+	for _, api := range supported {
+		if c.api == api {
+			return c
+		}
+	}
+	unsupportedErr := fmt.Errorf("%w: %s service can't be used with the %s API", ErrUnsupportedForAPI, service, c.api)
+	gql := graphql.NewClient(c.gql.ShopDomain(), nil)
+	gql.SetTracer(tracer.Noop)
+	gql.Use(func(graphql.Handler) graphql.Handler {
+		return func(context.Context, *graphql.Request) (*graphql.Response, error) {
+			return nil, unsupportedErr
+		}
+	})
+	unsupported := &Client{gql: gql, api: c.api, version: c.version, log: c.log}
+	unsupported.setServices(func(string, ...API) *Client { return unsupported })
+	return unsupported
+}
+
 func newShopifyGraphQLClientWithToken(token string, storeName string) *graphql.Client {
 	opts := []graphqlclient.Option{
-		graphqlclient.WithVersion(shopifyAPIVersion),
+		graphqlclient.WithVersion(string(shopifyAPIVersion)),
 		graphqlclient.WithToken(token),
 	}
 	// todo no more fixed storeName
@@ -168,7 +244,7 @@ func newShopifyGraphQLClientWithToken(token string, storeName string) *graphql.C
 
 func newShopifyStoreFrontGraphQLClientWithToken(token string, storeName string) *graphql.Client {
 	opts := []graphqlclient.Option{
-		graphqlclient.WithStoreFrontVersion(shopifyStoreFrontAPIVersion),
+		graphqlclient.WithStoreFrontVersion(string(shopifyStoreFrontAPIVersion)),
 		graphqlclient.WithStoreFrontToken(token),
 	}
 	// todo no more fixed storeName
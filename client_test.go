@@ -0,0 +1,91 @@
+package shopify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	graphqlclient "github.com/gempages/go-shopify-graphql/graph"
+)
+
+func TestNewSetsEveryService(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+		api  API
+		err  error
+	}{
+		{name: "access token", opt: WithAccessToken("token"), api: AdminAPI},
+		{name: "private app", opt: WithPrivateAppAuth("key", "password"), api: AdminAPI},
+		{name: "storefront token", opt: WithStorefrontToken("token"), api: StorefrontAPI},
+		{name: "storefront private token", opt: WithStorefrontPrivateToken("token"), api: StorefrontAPI},
+		{name: "no auth", opt: WithAPIVersion(APIVersion2024_10), err: ErrMissingAuth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New("example.myshopify.com", tt.opt)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("expected (%v), got (%v)", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.API() != tt.api {
+				t.Errorf("expected (%v), got (%v)", tt.api, c.API())
+			}
+			v := reflect.ValueOf(c).Elem()
+			for i := 0; i < v.NumField(); i++ {
+				f := v.Field(i)
+				if f.Kind() == reflect.Interface && f.IsNil() {
+					t.Errorf("expected %s to be set", v.Type().Field(i).Name)
+				}
+			}
+		})
+	}
+}
+
+func TestNewFailsUnsupportedServices(t *testing.T) {
+	var got *http.Request
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"data":{"node":null}}`)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	})
+
+	c, err := New("example.myshopify.com", WithStorefrontPrivateToken("token"),
+		WithGraphQLOptions(graphqlclient.WithBaseTransport(base)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Order.Get(context.Background(), "gid://shopify/Order/1")
+	if !errors.Is(err, ErrUnsupportedForAPI) {
+		t.Errorf("expected (%v), got (%v)", ErrUnsupportedForAPI, err)
+	}
+	// ListAll calls the BulkOperation service of the Order service's client.
+	_, err = c.Order.ListAll(context.Background())
+	if !errors.Is(err, ErrUnsupportedForAPI) {
+		t.Errorf("expected (%v), got (%v)", ErrUnsupportedForAPI, err)
+	}
+	if got != nil {
+		t.Errorf("expected no request to be sent, got %v", got.URL)
+	}
+
+	_, _ = c.Cart.Get(context.Background(), "gid://shopify/Cart/1")
+	if got == nil {
+		t.Fatal("expected the cart request to be sent")
+	}
+	if got.URL.Path != "/api/graphql.json" || got.Header.Get("Shopify-Storefront-Private-Token") != "token" {
+		t.Errorf("unexpected request %v %v", got.URL, got.Header)
+	}
+}
@@ -36,7 +36,7 @@ func IsInvalidStorefrontTokenError(err error) bool {
 }
 
 func IsPermissionError(err error) bool {
-	return err != nil && strings.Contains(err.Error(), "403 Forbidden")
+	return err != nil && (graphql.HasErrorCode(err, graphql.AccessDenied) || strings.Contains(err.Error(), "403 Forbidden"))
 }
 
 func IsPaymentRequiredError(err error) bool {
@@ -51,13 +51,19 @@ func IsRateLimitError(err error) bool {
 	if err == nil {
 		return false
 	}
-	return errors.Is(err, graphql.ErrMaxCostExceeded) ||
+	return errors.Is(err, graphql.ErrMaxCostExceeded) || errors.Is(err, graphql.ErrTooManyRequests) ||
+		graphql.HasErrorCode(err, graphql.Throttled) ||
 		strings.Contains(err.Error(), "Reduce request rates to resume uninterrupted service") ||
 		strings.Contains(err.Error(), "The rate of change to")
 }
 
 func IsNotExistError(err error) bool {
-	return err != nil && (strings.Contains(err.Error(), "doesn't exist") || strings.Contains(err.Error(), "does not exist"))
+	if err == nil {
+		return false
+	}
+	// Most mutations return userErrors without a code when the resource doesn't exist.
+	return HasUserErrorCode(err, string(model.FilesErrorCodeFileDoesNotExist)) ||
+		strings.Contains(err.Error(), "doesn't exist") || strings.Contains(err.Error(), "does not exist")
 }
 
 // IsValidationDiscountError checks if the error indicates that the active period in discount overlaps with another price rule.
@@ -74,7 +80,9 @@ func IsValidationDiscountError(err error) bool {
 }
 
 func IsFileNotExistError(err error) bool {
-	return err != nil && strings.Contains(err.Error(), "FILE_DOES_NOT_EXIST")
+	// Errors of other file mutations are still built from the printed userErrors.
+	return err != nil && (HasUserErrorCode(err, string(model.FilesErrorCodeFileDoesNotExist)) ||
+		strings.Contains(err.Error(), string(model.FilesErrorCodeFileDoesNotExist)))
 }
 
 func IsUnauthorizedError(err error) bool {
@@ -101,6 +109,89 @@ func IsGatewayTimeout(err error) bool {
 	return err != nil && errors.Is(err, graphql.ErrGatewayTimeout)
 }
 
+// IsOutcomeUnknownError checks if a mutation failed without telling whether Shopify applied it.
+func IsOutcomeUnknownError(err error) bool {
+	return err != nil && errors.Is(err, graphql.ErrOutcomeUnknown)
+}
+
 func IsAddressTakenError(err error) bool {
-	return err != nil && strings.Contains(err.Error(), "Address for this topic has already been taken")
+	// Webhook subscription userErrors have no code, the message is all there is to check.
+	return err != nil && (HasUserErrorCode(err, UserErrorCodeTaken) ||
+		strings.Contains(err.Error(), "Address for this topic has already been taken"))
+}
+
+// IsShopCoolingDownError checks if a ClientPool refused to call a shop that recently answered with
+// ErrLocked, ErrPaymentRequired or ErrUnauthorized.
+func IsShopCoolingDownError(err error) bool {
+	return err != nil && errors.Is(err, ErrShopCoolingDown)
+}
+
+// UserErrorCodeTaken is the code of userErrors about a value already used, e.g. a webhook address.
+const UserErrorCodeTaken = "TAKEN"
+
+// MutationUserError is an error returned in the userErrors of a mutation payload: the mutation ran,
+// but the input was rejected. Unlike graphql.Errors, which means the operation itself failed.
+type MutationUserError struct {
+	// Field is the path to the input field that caused the error.
+	Field   []string `json:"field"`
+	Message string   `json:"message"`
+	// Code is the error code, e.g. "FILE_DOES_NOT_EXIST". Empty for mutations whose userErrors have none.
+	Code string `json:"code"`
+}
+
+func (e MutationUserError) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (%s)", e.Message, e.Code)
+}
+
+// MutationUserErrors is returned by services when a mutation answers with userErrors.
+// Use errors.As to get it from an error returned by a service.
+type MutationUserErrors []MutationUserError
+
+func (e MutationUserErrors) Error() string {
+	messages := make([]string, len(e))
+	for i := range e {
+		messages[i] = e[i].Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// HasCode reports whether any of the errors has the given code.
+func (e MutationUserErrors) HasCode(code string) bool {
+	for i := range e {
+		if e[i].Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// HasUserErrorCode reports whether err contains MutationUserErrors with the given code.
+func HasUserErrorCode(err error, code string) bool {
+	var userErrs MutationUserErrors
+	return errors.As(err, &userErrs) && userErrs.HasCode(code)
+}
+
+// filesUserErrors returns the userErrors of a file mutation as MutationUserErrors.
+func filesUserErrors(userErrors []model.FilesUserError) MutationUserErrors {
+	errs := make(MutationUserErrors, len(userErrors))
+	for i, userErr := range userErrors {
+		errs[i] = MutationUserError{Field: userErr.Field, Message: userErr.Message}
+		if userErr.Code != nil {
+			errs[i].Code = string(*userErr.Code)
+		}
+	}
+	return errs
+}
+
+// modelUserErrors returns userErrors without code, e.g. the ones of webhook subscription mutations,
+// as MutationUserErrors.
+func modelUserErrors(userErrors []model.UserError) MutationUserErrors {
+	errs := make(MutationUserErrors, len(userErrors))
+	for i, userErr := range userErrors {
+		errs[i] = MutationUserError{Field: userErr.Field, Message: userErr.Message}
+	}
+	return errs
 }
@@ -0,0 +1,62 @@
+package shopify
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gempages/go-shopify-graphql-model/graph/model"
+
+	"github.com/gempages/go-shopify-graphql/graphql"
+)
+
+func TestMutationUserErrors(t *testing.T) {
+	code := model.FilesErrorCodeFileDoesNotExist
+	fileErr := fmt.Errorf("delete: %w", filesUserErrors([]model.FilesUserError{
+		{Code: &code, Field: []string{"fileIds"}, Message: "File id gid://shopify/MediaImage/1 does not exist."},
+	}))
+	if !IsFileNotExistError(fileErr) {
+		t.Errorf("expected (%v), got (%v)", true, false)
+	}
+	if !IsNotExistError(fileErr) {
+		t.Errorf("expected (%v), got (%v)", true, false)
+	}
+	// userErrors aren't GraphQL errors: the mutation itself ran.
+	var gqlErrs graphql.Errors
+	if errors.As(fileErr, &gqlErrs) {
+		t.Errorf("expected userErrors not to be graphql.Errors, got (%v)", gqlErrs)
+	}
+	want := "delete: File id gid://shopify/MediaImage/1 does not exist. (FILE_DOES_NOT_EXIST)"
+	if fileErr.Error() != want {
+		t.Errorf("expected (%v), got (%v)", want, fileErr.Error())
+	}
+
+	takenErr := modelUserErrors([]model.UserError{
+		{Field: []string{"webhookSubscription", "callbackUrl"}, Message: "Address for this topic has already been taken"},
+	})
+	if !IsAddressTakenError(takenErr) {
+		t.Errorf("expected (%v), got (%v)", true, false)
+	}
+	if IsFileNotExistError(takenErr) {
+		t.Errorf("expected (%v), got (%v)", false, true)
+	}
+	if !IsAddressTakenError(MutationUserErrors{{Message: "Address is taken", Code: UserErrorCodeTaken}}) {
+		t.Errorf("expected (%v), got (%v)", true, false)
+	}
+	if IsAddressTakenError(modelUserErrors([]model.UserError{{Message: "Address is invalid"}})) {
+		t.Errorf("expected (%v), got (%v)", false, true)
+	}
+}
+
+func TestErrorsFromPrintedUserErrors(t *testing.T) {
+	// Most services still return userErrors printed with %+v.
+	code := model.FilesErrorCodeFileDoesNotExist
+	fileErr := fmt.Errorf("%+v", []MutationUserError{{Message: "File does not exist", Code: string(code)}})
+	if !IsFileNotExistError(fileErr) {
+		t.Errorf("expected (%v), got (%v)", true, false)
+	}
+	takenErr := fmt.Errorf("%+v", []model.UserError{{Message: "Address for this topic has already been taken"}})
+	if !IsAddressTakenError(takenErr) {
+		t.Errorf("expected (%v), got (%v)", true, false)
+	}
+}
@@ -348,7 +348,7 @@ func (s *FileServiceOp) Delete(ctx context.Context, fileID []graphql.ID) ([]stri
 	}
 
 	if len(m.FileDeleteResult.UserErrors) > 0 {
-		return nil, fmt.Errorf("%+v", m.FileDeleteResult.UserErrors)
+		return nil, filesUserErrors(m.FileDeleteResult.UserErrors)
 	}
 
 	return m.FileDeleteResult.DeletedFileIds, nil
@@ -3,13 +3,17 @@ package graphqlclient
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gempages/go-shopify-graphql/graphql"
+	"github.com/gempages/go-shopify-graphql/metrics"
+	"github.com/gempages/go-shopify-graphql/tracer"
 )
 
 const (
-	shopifyAccessTokenHeader           = "X-Shopify-Access-Token"
-	shopifyStoreFrontAccessTokenHeader = "X-Shopify-Storefront-Access-Token"
+	shopifyAccessTokenHeader            = "X-Shopify-Access-Token"
+	shopifyStoreFrontAccessTokenHeader  = "X-Shopify-Storefront-Access-Token"
+	shopifyStoreFrontPrivateTokenHeader = "Shopify-Storefront-Private-Token"
 )
 
 var (
@@ -22,7 +26,8 @@ var (
 // Option is used to configure options
 type Option func(t *transport)
 
-// WithVersion optionally sets the API version if the passed string is valid
+// WithVersion optionally sets the API version, unless it is empty or "default". The version isn't validated
+// here, the clients of the shopify package validate the version set by their options with Version
 func WithVersion(graphqlApiVersion string) Option {
 	return func(t *transport) {
 		if graphqlApiVersion != "" && graphqlApiVersion != defaultAPIVersion {
@@ -53,6 +58,14 @@ func WithStoreFrontToken(token string) Option {
 	}
 }
 
+// WithStoreFrontPrivateToken optionally sets the private Storefront API token, meant for server-side requests
+func WithStoreFrontPrivateToken(token string) Option {
+	return func(t *transport) {
+		t.storeFrontPrivateToken = token
+		t.apiPath = "api"
+	}
+}
+
 // WithPrivateAppAuth optionally sets private app credentials
 func WithPrivateAppAuth(apiKey string, password string) Option {
 	return func(t *transport) {
@@ -61,13 +74,72 @@ func WithPrivateAppAuth(apiKey string, password string) Option {
 	}
 }
 
+// WithRetryPolicy optionally sets the policy deciding whether and when failed requests are retried
+func WithRetryPolicy(policy graphql.RetryPolicy) Option {
+	return func(t *transport) {
+		t.retryPolicy = policy
+	}
+}
+
+// WithBaseTransport optionally sets the transport requests are sent through after the auth headers are added,
+// e.g. to use a proxy, custom TLS settings or a test double. Defaults to http.DefaultTransport
+func WithBaseTransport(base http.RoundTripper) Option {
+	return func(t *transport) {
+		t.base = base
+	}
+}
+
+// WithHTTPClient optionally sets the HTTP client used to send requests. Its transport is wrapped to add
+// the auth headers, its other settings (timeout, cookie jar, redirect policy) are kept
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(t *transport) {
+		t.httpClient = httpClient
+	}
+}
+
+// WithTimeout optionally sets the time limit for a single request, including reading the response body
+func WithTimeout(timeout time.Duration) Option {
+	return func(t *transport) {
+		t.timeout = timeout
+	}
+}
+
+// WithTracer optionally sets the tracer reporting requests, e.g. tracer.NewOpenTelemetryTracer. Defaults to Sentry
+func WithTracer(t tracer.Tracer) Option {
+	return func(t2 *transport) {
+		t2.tracer = t
+	}
+}
+
+// WithMetrics optionally sets the recorder receiving the metrics of requests, e.g. an adapter to Prometheus
+func WithMetrics(recorder metrics.Recorder) Option {
+	return func(t *transport) {
+		t.metrics = recorder
+	}
+}
+
+// WithDeprecationHandler optionally sets the function called for every request Shopify flags as using deprecated API features
+func WithDeprecationHandler(h graphql.DeprecationHandler) Option {
+	return func(t *transport) {
+		t.onDeprecation = h
+	}
+}
+
 type transport struct {
-	accessToken           string
-	storeFrontAccessToken string
-	apiKey                string
-	password              string
-	apiVersion            string
-	apiPath               string
+	accessToken            string
+	storeFrontAccessToken  string
+	storeFrontPrivateToken string
+	apiKey                 string
+	password               string
+	apiVersion             string
+	apiPath                string
+	retryPolicy            graphql.RetryPolicy
+	base                   http.RoundTripper
+	httpClient             *http.Client
+	timeout                time.Duration
+	tracer                 tracer.Tracer
+	metrics                metrics.Recorder
+	onDeprecation          graphql.DeprecationHandler
 }
 
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -77,9 +149,24 @@ func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		req.SetBasicAuth(t.apiKey, t.password)
 	} else if t.storeFrontAccessToken != "" {
 		req.Header.Set(shopifyStoreFrontAccessTokenHeader, t.storeFrontAccessToken)
+	} else if t.storeFrontPrivateToken != "" {
+		req.Header.Set(shopifyStoreFrontPrivateTokenHeader, t.storeFrontPrivateToken)
+	}
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
 	}
+	return base.RoundTrip(req)
+}
 
-	return http.DefaultTransport.RoundTrip(req)
+// Version returns the API version set by opts, the last one winning, or "default" if none sets it
+func Version(opts ...Option) string {
+	trans := &transport{apiVersion: defaultAPIVersion}
+	for _, opt := range opts {
+		opt(trans)
+	}
+	return trans.apiVersion
 }
 
 // NewClient creates a new client (in fact, just a simple wrapper for a graphql.Client)
@@ -93,9 +180,34 @@ func NewClient(shopifyDomain string, opts ...Option) *graphql.Client {
 		opt(trans)
 	}
 
-	httpClient := &http.Client{Transport: trans}
+	httpClient := &http.Client{}
+	if trans.httpClient != nil {
+		*httpClient = *trans.httpClient
+		if trans.base == nil {
+			trans.base = trans.httpClient.Transport
+		}
+	}
+	httpClient.Transport = trans
+	if trans.timeout > 0 {
+		httpClient.Timeout = trans.timeout
+	}
 	url := buildAPIEndpoint(shopifyDomain, trans.apiPath, trans.apiVersion)
 	graphClient := graphql.NewClient(url, httpClient)
+	graphClient.SetVersionURL(func(version string) string {
+		return buildAPIEndpoint(shopifyDomain, trans.apiPath, version)
+	})
+	if trans.retryPolicy != nil {
+		graphClient.SetRetryPolicy(trans.retryPolicy)
+	}
+	if trans.tracer != nil {
+		graphClient.SetTracer(trans.tracer)
+	}
+	if trans.metrics != nil {
+		graphClient.SetMetrics(trans.metrics)
+	}
+	if trans.onDeprecation != nil {
+		graphClient.SetDeprecationHandler(trans.onDeprecation)
+	}
 	return graphClient
 }
 
@@ -0,0 +1,110 @@
+package graphqlclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gempages/go-shopify-graphql/graphql"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestNewClientWithBaseTransport(t *testing.T) {
+	var got *http.Request
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"data":{"shop":{"name":"kyle"}}}`)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	})
+
+	c := NewClient("example.myshopify.com", WithToken("token"), WithVersion("2024-10"), WithBaseTransport(base))
+	var v struct {
+		Shop struct {
+			Name string
+		}
+	}
+	err := c.QueryString(context.Background(), "{shop{name}}", nil, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		t.Fatal("expected request to go through the base transport")
+	}
+	if want := "https://example.myshopify.com/admin/api/2024-10/graphql.json"; got.URL.String() != want {
+		t.Errorf("expected (%v), got (%v)", want, got.URL)
+	}
+	if token := got.Header.Get(shopifyAccessTokenHeader); token != "token" {
+		t.Errorf("expected access token header, got (%v)", token)
+	}
+	if v.Shop.Name != "kyle" {
+		t.Errorf("unexpected data %+v", v)
+	}
+}
+
+func TestNewClientWithHTTPClientAndTimeout(t *testing.T) {
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	httpClient := &http.Client{Transport: base}
+
+	c := NewClient("example.myshopify.com", WithToken("token"), WithHTTPClient(httpClient), WithTimeout(50*time.Millisecond))
+	var v interface{}
+	t1 := time.Now()
+	err := c.QueryString(context.Background(), "{shop{name}}", nil, &v)
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if elapsed := time.Since(t1); elapsed > time.Second {
+		t.Errorf("expected request to time out, took %v", elapsed)
+	}
+	if _, ok := httpClient.Transport.(roundTripFunc); !ok {
+		t.Error("expected caller's client to be left untouched")
+	}
+}
+
+func TestNewClientWithAPIVersionOverride(t *testing.T) {
+	var urls []string
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		urls = append(urls, req.URL.String())
+		if token := req.Header.Get(shopifyAccessTokenHeader); token != "token" {
+			t.Errorf("expected access token header, got (%v)", token)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"data":{"shop":{"name":"kyle"}}}`)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	})
+
+	c := NewClient("example.myshopify.com", WithToken("token"), WithVersion("2024-10"), WithBaseTransport(base))
+	var v interface{}
+	err := c.QueryString(graphql.WithAPIVersion(context.Background(), "2025-01"), "{shop{name}}", nil, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.QueryString(context.Background(), "{shop{name}}", nil, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"https://example.myshopify.com/admin/api/2025-01/graphql.json",
+		"https://example.myshopify.com/admin/api/2024-10/graphql.json",
+	}
+	if strings.Join(urls, " ") != strings.Join(want, " ") {
+		t.Errorf("expected (%v), got (%v)", want, urls)
+	}
+}
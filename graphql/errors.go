@@ -2,6 +2,15 @@ package graphql
 
 import (
 	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	pkghttp "github.com/gempages/go-shopify-graphql/http"
 )
 
 var (
@@ -9,8 +18,8 @@ var (
 	ErrLocked = errors.New("locked")
 	// ErrPaymentRequired means the shop is frozen. The shop owner will need to pay the outstanding balance to unfreeze the shop.
 	ErrPaymentRequired = errors.New("payment required")
-	// ErrMaxCostExceeded means API rate limit has been reached. The API supports a maximum of 1000 cost points per app per store per minute.
-	// This quota replenishes at a rate of 50 cost points per second.
+	// ErrMaxCostExceeded means the requested cost of a single query is higher than the maximum the shop's cost bucket can hold.
+	// Retrying the same query can't succeed; it has to be split or its page sizes reduced.
 	ErrMaxCostExceeded    = errors.New("max cost exceeded")
 	ErrUnauthorized       = errors.New("unauthorized")
 	ErrForbidden          = errors.New("forbidden")
@@ -18,4 +27,213 @@ var (
 	ErrInternal           = errors.New("internal error")
 	ErrServiceUnavailable = errors.New("service unavailable")
 	ErrGatewayTimeout     = errors.New("gateway timeout")
+	// ErrTooManyRequests means Shopify answered with HTTP 429. The returned error is a *TooManyRequestsError
+	// carrying how long Shopify asked us to wait before the next request.
+	ErrTooManyRequests = errors.New("too many requests")
+	// ErrOutcomeUnknown means a mutation failed in a way that doesn't tell whether Shopify applied it,
+	// e.g. a timeout or a 5xx response. Callers should check the resource before trying again.
+	ErrOutcomeUnknown = errors.New("mutation outcome unknown")
+	// ErrVersionOverrideUnsupported means WithAPIVersion was used with a client that doesn't know how to build
+	// the endpoint of another API version.
+	ErrVersionOverrideUnsupported = errors.New("API version override unsupported")
 )
+
+// Errors represents the "errors" array in a response from a GraphQL server.
+// If returned via error interface, the slice is expected to contain at least 1 element.
+// Use errors.As to get it from an error returned by the Client.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Errors.
+type Errors []Error
+
+// Error is a single error of a GraphQL response.
+type Error struct {
+	Message   string     `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+	// Path is the path of the response field which experienced the error, made of field names and list indices.
+	Path []any `json:"path,omitempty"`
+	// Extensions holds everything Shopify adds to the error, such as "code", "documentation" or "cost".
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// Location is a position in the GraphQL document an error refers to.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Code returns the "extensions.code" of the error, or an empty string if it has none.
+func (e Error) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// Error implements error interface.
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i := range e {
+		messages[i] = e[i].Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// HasCode reports whether any of the errors has the given "extensions.code".
+func (e Errors) HasCode(code string) bool {
+	for i := range e {
+		if e[i].Code() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// HasErrorCode reports whether err contains GraphQL errors with the given "extensions.code".
+func HasErrorCode(err error, code string) bool {
+	var gqlErrs Errors
+	return errors.As(err, &gqlErrs) && gqlErrs.HasCode(code)
+}
+
+// TooManyRequestsError is returned for HTTP 429 responses. It matches ErrTooManyRequests with errors.Is.
+type TooManyRequestsError struct {
+	// RetryAfter is the duration parsed from the Retry-After header, zero if the header is missing or invalid.
+	RetryAfter time.Duration
+}
+
+func (e *TooManyRequestsError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s: retry after %s", ErrTooManyRequests, e.RetryAfter)
+	}
+	return ErrTooManyRequests.Error()
+}
+
+func (e *TooManyRequestsError) Is(target error) bool {
+	return target == ErrTooManyRequests
+}
+
+// retryAfter returns the delay requested by the server for err, or zero if none was given.
+func retryAfter(err error) time.Duration {
+	var tooManyErr *TooManyRequestsError
+	if errors.As(err, &tooManyErr) {
+		return tooManyErr.RetryAfter
+	}
+	return 0
+}
+
+// PartialDataError is returned when a response carries both data and errors. The data has been decoded
+// into the value passed to the Client; only the fields at the paths of Errors are missing or null.
+type PartialDataError struct {
+	Errors Errors
+}
+
+func (e *PartialDataError) Error() string {
+	return "partial data: " + e.Errors.Error()
+}
+
+func (e *PartialDataError) Unwrap() error {
+	return e.Errors
+}
+
+// ForPath returns the errors whose path starts with prefix, e.g. ForPath("nodes", 1) for the second node.
+func (e Errors) ForPath(prefix ...any) Errors {
+	var out Errors
+	for i := range e {
+		if len(e[i].Path) < len(prefix) {
+			continue
+		}
+		match := true
+		for j := range prefix {
+			// Indices are decoded from JSON as float64, so compare their printed form.
+			if fmt.Sprint(e[i].Path[j]) != fmt.Sprint(prefix[j]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			out = append(out, e[i])
+		}
+	}
+	return out
+}
+
+// IsRetryableError reports whether err is transient. Responses carrying partial data are never retried.
+// ErrMaxCostExceeded is deliberately excluded: a query that costs more than the bucket can ever hold
+// fails the same way on every attempt.
+func IsRetryableError(err error) bool {
+	var partialErr *PartialDataError
+	if errors.As(err, &partialErr) {
+		return false
+	}
+	if uerr, isURLErr := err.(*url.Error); isURLErr {
+		return uerr.Timeout() || uerr.Temporary()
+	}
+	return isThrottledError(err) || pkghttp.IsConnectionError(err) || errors.Is(err, ErrTooManyRequests) ||
+		errors.Is(err, ErrGatewayTimeout) || errors.Is(err, ErrServiceUnavailable)
+}
+
+func isThrottledError(err error) bool {
+	return err != nil && (HasErrorCode(err, Throttled) || err.Error() == "Throttled")
+}
+
+// isUnsentError reports whether err proves the operation wasn't run: either the connection to Shopify
+// couldn't be established, or Shopify rejected the request for rate limiting before executing it.
+func isUnsentError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	return isThrottledError(err) || errors.Is(err, ErrTooManyRequests) || errors.Is(err, ErrMaxCostExceeded)
+}
+
+// isOutcomeUnknownError reports whether err may have happened after the operation was run by Shopify.
+func isOutcomeUnknownError(err error) bool {
+	if isUnsentError(err) {
+		return false
+	}
+	var uerr *url.Error
+	return errors.As(err, &uerr) || pkghttp.IsConnectionError(err) || errors.Is(err, ErrInternal) ||
+		errors.Is(err, ErrServiceUnavailable) || errors.Is(err, ErrGatewayTimeout)
+}
+
+// parseRetryAfter parses a Retry-After header value, given either in (possibly fractional) seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// RequestIDError annotates an error with the X-Request-Id of the Shopify response it came from,
+// which Shopify support asks for when investigating a failed call.
+type RequestIDError struct {
+	RequestID string
+	Err       error
+}
+
+func (e *RequestIDError) Error() string {
+	return fmt.Sprintf("%s (request id: %s)", e.Err, e.RequestID)
+}
+
+func (e *RequestIDError) Unwrap() error {
+	return e.Err
+}
+
+// RequestID returns the X-Request-Id of the response err came from, or an empty string if there is none.
+func RequestID(err error) string {
+	var reqIDErr *RequestIDError
+	if errors.As(err, &reqIDErr) {
+		return reqIDErr.RequestID
+	}
+	return ""
+}
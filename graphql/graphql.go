@@ -2,31 +2,45 @@ package graphql
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
+	"sync/atomic"
 	"time"
 
 	"github.com/gempages/go-helper/errors"
-	gpstrings "github.com/gempages/go-helper/strings"
-	"github.com/gempages/go-helper/tracing"
-	"github.com/getsentry/sentry-go"
 	"golang.org/x/net/context/ctxhttp"
 
-	pkghttp "github.com/gempages/go-shopify-graphql/http"
+	"github.com/gempages/go-shopify-graphql/metrics"
+	"github.com/gempages/go-shopify-graphql/tracer"
 	"github.com/gempages/go-shopify-graphql/utils"
 )
 
-const MaxCostExceeded = "MAX_COST_EXCEEDED"
+// Error codes found in the "extensions.code" field of GraphQL errors returned by Shopify.
+const (
+	MaxCostExceeded = "MAX_COST_EXCEEDED"
+	Throttled       = "THROTTLED"
+	AccessDenied    = "ACCESS_DENIED"
+)
 
 // Client is a GraphQL client.
 type Client struct {
-	url        string // GraphQL server URL.
-	httpClient *http.Client
-	retries    int
+	url         string // GraphQL server URL.
+	httpClient  *http.Client
+	retryPolicy RetryPolicy
+	middlewares []Middleware
+	tracer      tracer.Tracer
+	metrics     metrics.Recorder
+	shopDomain  string
+
+	onDeprecation DeprecationHandler
+	versionURL    func(version string) string
+
+	costs    costCache // query -> last requested cost, used to reserve points before sending.
+	lastCost atomic.Pointer[QueryCost]
 }
 
 // NewClient creates a GraphQL client targeting the specified GraphQL server URL.
@@ -36,22 +50,95 @@ func NewClient(url string, httpClient *http.Client) *Client {
 		httpClient = http.DefaultClient
 	}
 	return &Client{
-		url:        url,
-		httpClient: httpClient,
+		url:         url,
+		httpClient:  httpClient,
+		retryPolicy: NoRetry,
+		tracer:      tracer.Default,
+		metrics:     metrics.Noop,
+		shopDomain:  hostOf(url),
 	}
 }
 
-// SetRetries set a context for graphql client
-// set input ctx for graphql client
+// SetRetries makes the client attempt an operation up to retries times,
+// waiting one more second before each new attempt.
 func (c *Client) SetRetries(retries int) {
-	c.retries = retries
+	c.retryPolicy = linearRetry{retries: retries}
+}
+
+// SetRetryPolicy replaces the policy deciding whether and when failed operations are retried.
+// A nil policy disables retries.
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	if policy == nil {
+		policy = NoRetry
+	}
+	c.retryPolicy = policy
+}
+
+// SetTracer sets the tracer reporting the client's operations. A nil tracer disables tracing.
+func (c *Client) SetTracer(t tracer.Tracer) {
+	if t == nil {
+		t = tracer.Noop
+	}
+	c.tracer = t
+}
+
+// Tracer returns the tracer reporting the client's operations.
+func (c *Client) Tracer() tracer.Tracer {
+	return c.tracer
+}
+
+// SetMetrics sets the recorder receiving the metrics of the client's operations. A nil recorder disables metrics.
+func (c *Client) SetMetrics(m metrics.Recorder) {
+	if m == nil {
+		m = metrics.Noop
+	}
+	c.metrics = m
+}
+
+// Metrics returns the recorder receiving the metrics of the client's operations.
+func (c *Client) Metrics() metrics.Recorder {
+	return c.metrics
+}
+
+// SetDeprecationHandler sets the function called for every operation Shopify flags as using deprecated API features.
+func (c *Client) SetDeprecationHandler(h DeprecationHandler) {
+	c.onDeprecation = h
+}
+
+// SetVersionURL sets the function returning the endpoint of an API version,
+// which enables overriding the version of a call with WithAPIVersion.
+func (c *Client) SetVersionURL(versionURL func(version string) string) {
+	c.versionURL = versionURL
+}
+
+// ShopDomain returns the domain of the shop the client talks to.
+func (c *Client) ShopDomain() string {
+	return c.shopDomain
+}
+
+// Use appends middlewares to the chain every operation goes through. They run after tracing starts
+// and before retries, so each middleware sees an operation once, with the response of its last attempt.
+// Use is not safe to call concurrently with operations.
+func (c *Client) Use(middlewares ...Middleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// RateLimitStatus returns the cost information of the last response received by the client,
+// or nil if no response carrying cost information has been received yet.
+func (c *Client) RateLimitStatus() *QueryCost {
+	cost := c.lastCost.Load()
+	if cost == nil {
+		return nil
+	}
+	out := *cost
+	return &out
 }
 
 // QueryString executes a single GraphQL query request,
 // using the given raw query `q` and populating the response into the `v`.
 // `q` should be a correct GraphQL request string that corresponds to the GraphQL schema.
 func (c *Client) QueryString(ctx context.Context, q string, variables map[string]interface{}, v interface{}) error {
-	return c.do(ctx, q, variables, v)
+	return c.do(ctx, QueryOperation, q, variables, v)
 }
 
 // Query executes a single GraphQL query request,
@@ -59,7 +146,9 @@ func (c *Client) QueryString(ctx context.Context, q string, variables map[string
 // q should be a pointer to struct that corresponds to the GraphQL schema.
 func (c *Client) Query(ctx context.Context, q interface{}, variables map[string]interface{}) error {
 	query := constructQuery(q, variables)
-	return c.do(ctx, query, variables, q)
+	req := c.newRequest(ctx, QueryOperation, query, variables, q)
+	req.graphQLResult = true
+	return c.execute(ctx, req)
 }
 
 // Mutate executes a single GraphQL mutation request,
@@ -67,171 +156,163 @@ func (c *Client) Query(ctx context.Context, q interface{}, variables map[string]
 // m should be a pointer to struct that corresponds to the GraphQL schema.
 func (c *Client) Mutate(ctx context.Context, m interface{}, variables map[string]interface{}) error {
 	query := constructMutation(m, variables)
-	// return nil
-	return c.do(ctx, query, variables, m)
+	req := c.newRequest(ctx, MutationOperation, query, variables, m)
+	req.graphQLResult = true
+	return c.execute(ctx, req)
 }
 
 // MutateString executes a single GraphQL mutation request,
 // using the given raw query `m` and populating the response into it.
 // `m` should be a correct GraphQL mutation request string that corresponds to the GraphQL schema.
 func (c *Client) MutateString(ctx context.Context, m string, variables map[string]interface{}, v interface{}) error {
-	return c.do(ctx, m, variables, v)
+	return c.do(ctx, MutationOperation, m, variables, v)
+}
+
+// do executes a single GraphQL operation through the middleware chain.
+func (c *Client) do(ctx context.Context, op OperationType, query string, variables map[string]interface{}, v interface{}) error {
+	return c.execute(ctx, c.newRequest(ctx, op, query, variables, v))
+}
+
+func (c *Client) newRequest(ctx context.Context, op OperationType, query string, variables map[string]interface{}, v interface{}) *Request {
+	req := &Request{
+		Operation:     op,
+		OperationName: utils.GetDescriptionFromQuery(query),
+		URL:           c.url,
+		Query:         query,
+		Variables:     variables,
+		Header:        make(http.Header),
+		Result:        v,
+	}
+	if version := apiVersion(ctx); version != "" && c.versionURL != nil {
+		req.URL = c.versionURL(version)
+	}
+	return req
+}
+
+func (c *Client) execute(ctx context.Context, req *Request) error {
+	resp, err := c.handler()(ctx, req)
+	return c.observe(ctx, req, resp, err)
 }
 
-// do executes a single GraphQL operation.
-func (c *Client) do(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error {
-	var err error
+// handler builds the middleware chain: tracing and metrics first, then the middlewares added with Use,
+// then retries, and finally a single HTTP round trip.
+func (c *Client) handler() Handler {
+	h := c.retry(c.send)
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		h = c.middlewares[i](h)
+	}
+	return c.tracing(c.metering(h))
+}
+
+// send executes a single attempt of req.
+func (c *Client) send(ctx context.Context, req *Request) (*Response, error) {
+	if version := apiVersion(ctx); version != "" && c.versionURL == nil {
+		return nil, fmt.Errorf("%w: %s", ErrVersionOverrideUnsupported, version)
+	}
 	in := struct {
 		Query     string                 `json:"query"`
 		Variables map[string]interface{} `json:"variables,omitempty"`
 	}{
-		Query:     query,
-		Variables: variables,
-	}
-
-	// sentry tracing
-	span := sentry.StartSpan(ctx, "shopify_graphql.send")
-	span.Description = utils.GetDescriptionFromQuery(query)
-	span.Data = map[string]interface{}{
-		"GraphQL Query":     query,
-		"GraphQL Variables": variables,
-		"URL":               c.url,
-	}
-	defer func() {
-		tracing.FinishSpan(span, err)
-	}()
-	ctx = span.Context()
-	// end sentry tracing
-
-	retries := c.retries
-	attempts := 0
-	for {
-		attempts++
-		// Create new data buffer for each attempt
-		var buf bytes.Buffer
-		err = json.NewEncoder(&buf).Encode(in)
-		if err != nil {
-			return err
-		}
-		err = c.doRequest(ctx, &buf, v)
-		if err == nil {
-			break
-		}
-		if retries <= 1 {
-			return fmt.Errorf("after %v attempts: %w", attempts, err)
-		}
-		if c.shouldRetry(err) {
-			retries--
-			time.Sleep(time.Duration(attempts) * time.Second)
-			continue
-		}
-		return err
+		Query:     req.Query,
+		Variables: req.Variables,
+	}
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(in)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, req.URL, &buf)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	httpReq.Header = req.Header.Clone()
+	httpReq.Header.Set("Content-Type", "application/json")
+	// Set explicitly so that custom transports get compressed responses too, doRequest decompresses them.
+	httpReq.Header.Set("Accept-Encoding", "gzip")
+	return c.doRequest(ctx, httpReq, req.Result, req.graphQLResult)
 }
 
-func (c *Client) doRequest(ctx context.Context, body io.Reader, v interface{}) error {
-	resp, err := ctxhttp.Post(ctx, c.httpClient, c.url, "application/json", body)
+// estimateCost returns the points to reserve for query before sending it,
+// based on what Shopify requested the last time the same query was sent.
+func (c *Client) estimateCost(query string) float64 {
+	if cost, ok := c.costs.get(query); ok {
+		return float64(cost)
+	}
+	return 1
+}
+
+func (c *Client) doRequest(ctx context.Context, httpReq *http.Request, v interface{}, graphQLResult bool) (*Response, error) {
+	resp, err := ctxhttp.Do(ctx, c.httpClient, httpReq)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
+	out := &Response{HTTPResponse: resp}
 	if resp.StatusCode == http.StatusPaymentRequired {
-		return ErrPaymentRequired
+		return out, ErrPaymentRequired
 	}
 	if resp.StatusCode == http.StatusLocked {
-		return ErrLocked
+		return out, ErrLocked
 	}
 	if resp.StatusCode == http.StatusUnauthorized {
-		return ErrUnauthorized
+		return out, ErrUnauthorized
 	}
 	if resp.StatusCode == http.StatusForbidden {
-		return ErrForbidden
+		return out, ErrForbidden
 	}
 	if resp.StatusCode == http.StatusNotFound {
-		return ErrNotFound
+		return out, ErrNotFound
 	}
 	if resp.StatusCode == http.StatusInternalServerError {
-		return ErrInternal
+		return out, ErrInternal
 	}
 	if resp.StatusCode == http.StatusServiceUnavailable {
-		return ErrServiceUnavailable
+		return out, ErrServiceUnavailable
 	}
 	if resp.StatusCode == http.StatusGatewayTimeout {
-		return ErrGatewayTimeout
+		return out, ErrGatewayTimeout
 	}
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return errors.NewErrorWithContext(ctx, fmt.Errorf("non-200 OK status code: %v", resp.Status), map[string]any{
-			"body": gpstrings.CutLength(string(body), 500)})
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return out, &TooManyRequestsError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
 	}
-	var out struct {
-		Data   *json.RawMessage
-		Errors graphErrors
-	}
-	err = json.NewDecoder(resp.Body).Decode(&out)
-	if err != nil {
-		body, _ := io.ReadAll(resp.Body)
-		return errors.NewErrorWithContext(ctx, fmt.Errorf("JSON decode response: %w", err), map[string]any{
-			"body": gpstrings.CutLength(string(body), 500)})
-	}
-	if out.Data != nil {
-		err := json.Unmarshal(*out.Data, v)
+	respBody := io.Reader(resp.Body)
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
 		if err != nil {
-			return errors.NewErrorWithContext(ctx, fmt.Errorf("unmarshal data: %w", err), map[string]any{
-				"out.Data": gpstrings.CutLength(string(*out.Data), 500)})
-		}
-	}
-	if len(out.Errors) > 0 {
-		for _, e := range out.Errors {
-			if e.Extensions.Code == MaxCostExceeded {
-				return ErrMaxCostExceeded
-			}
+			return out, fmt.Errorf("gzip response: %w", err)
 		}
-		return out.Errors
+		defer gz.Close()
+		respBody = gz
 	}
-	return nil
-}
-
-func (c *Client) shouldRetry(err error) bool {
-	if uerr, isURLErr := err.(*url.Error); isURLErr {
-		return uerr.Timeout() || uerr.Temporary()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(respBody, bodyContextLength))
+		return out, errors.NewErrorWithContext(ctx, fmt.Errorf("non-200 OK status code: %v", resp.Status), map[string]any{
+			"body": string(body)})
 	}
-	return isThrottledError(err) || pkghttp.IsConnectionError(err) || errors.Is(err, ErrMaxCostExceeded) ||
-		errors.Is(err, ErrGatewayTimeout) || errors.Is(err, ErrServiceUnavailable)
-}
-
-// errors represents the "errors" array in a response from a GraphQL server.
-// If returned via error interface, the slice is expected to contain at least 1 element.
-//
-// Specification: https://facebook.github.io/graphql/#sec-Errors.
-type graphErrors []struct {
-	Message    string
-	Extensions struct {
-		Code          string
-		Cost          int
-		MaxCost       int `json:"maxCost"`
-		Documentation string
+	head := &headRecorder{r: respBody}
+	body, err := decodeResponse(head, v, graphQLResult)
+	if err != nil {
+		return out, errors.NewErrorWithContext(ctx, fmt.Errorf("JSON decode response: %w", err), map[string]any{
+			"body": head.head.String()})
 	}
-	Locations []struct {
-		Line   int
-		Column int
+	out.Cost = body.Extensions.Cost
+	if len(body.Errors) > 0 {
+		if body.Errors.HasCode(MaxCostExceeded) {
+			return out, fmt.Errorf("%w: %w", ErrMaxCostExceeded, body.Errors)
+		}
+		if body.hasData {
+			return out, &PartialDataError{Errors: body.Errors}
+		}
+		return out, body.Errors
 	}
+	return out, nil
 }
 
-// Error implements error interface.
-func (e graphErrors) Error() string {
-	return e[0].Message
-}
-
-type operationType uint8
+// OperationType is the kind of a GraphQL operation.
+type OperationType uint8
 
 const (
-	queryOperation operationType = iota
-	mutationOperation
-	// subscriptionOperation // Unused.
+	QueryOperation OperationType = iota
+	MutationOperation
+	// SubscriptionOperation // Unused.
 )
-
-func isThrottledError(err error) bool {
-	return err != nil && err.Error() == "Throttled"
-}
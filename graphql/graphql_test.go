@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -15,13 +16,13 @@ import (
 // func TestDo(t *testing.T) {
 // }
 
-type Response struct {
+type testResponse struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
 }
 
-func MakeHTTPCall(url string) (*Response, error) {
+func MakeHTTPCall(url string) (*testResponse, error) {
 	resp, err := http.Get(url)
 	if err != nil {
 		return nil, err
@@ -30,7 +31,7 @@ func MakeHTTPCall(url string) (*Response, error) {
 	if err != nil {
 		return nil, err
 	}
-	r := &Response{}
+	r := &testResponse{}
 	if err := json.Unmarshal(body, r); err != nil {
 		return nil, err
 	}
@@ -41,7 +42,7 @@ func TestDo(t *testing.T) {
 	testTable := []struct {
 		name             string
 		server           *httptest.Server
-		expectedResponse *Response
+		expectedResponse *testResponse
 		expectedErr      error
 	}{
 		{
@@ -50,7 +51,7 @@ func TestDo(t *testing.T) {
 				w.WriteHeader(http.StatusOK)
 				w.Write([]byte(`{"id": 1, "name": "kyle", "description": "novice gopher"}`))
 			})),
-			expectedResponse: &Response{
+			expectedResponse: &testResponse{
 				ID:          1,
 				Name:        "kyle",
 				Description: "novice gopher",
@@ -77,7 +78,7 @@ func TestQuery(t *testing.T) {
 	testTable := []struct {
 		name             string
 		server           *httptest.Server
-		expectedResponse *Response
+		expectedResponse *testResponse
 		expectedErr      error
 	}{
 		{
@@ -101,7 +102,7 @@ func TestQuery(t *testing.T) {
 				// 	w.Write([]byte(`{"id": 1, "name": "kyle", "description": "novice gopher"}`))
 				// }
 			})),
-			expectedResponse: &Response{
+			expectedResponse: &testResponse{
 				ID:          1,
 				Name:        "kyle",
 				Description: "novice gopher",
@@ -116,7 +117,7 @@ func TestQuery(t *testing.T) {
 			var m map[string]interface{}
 			var v interface{}
 			t1 := time.Now()
-			_ = c.do(context.Background(), tc.name, m, v)
+			_ = c.do(context.Background(), QueryOperation, tc.name, m, v)
 			t2 := time.Now()
 			if t2.Sub(t1) > 2*time.Second {
 				t.Error("too much time")
@@ -168,3 +169,366 @@ func TestQuery(t *testing.T) {
 // 	// equals(t, []byte("OK"), body)
 
 // }
+
+func TestDoWaitsForThrottleStatus(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+		if calls == 1 {
+			w.Write([]byte(`{"errors":[{"message":"Throttled","extensions":{"code":"THROTTLED"}}],` +
+				`"extensions":{"cost":{"requestedQueryCost":100,"actualQueryCost":null,` +
+				`"throttleStatus":{"maximumAvailable":2000.0,"currentlyAvailable":0,"restoreRate":1000.0}}}}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"shop":{"name":"kyle"}},` +
+			`"extensions":{"cost":{"requestedQueryCost":100,"actualQueryCost":2,` +
+			`"throttleStatus":{"maximumAvailable":2000.0,"currentlyAvailable":1998,"restoreRate":1000.0}}}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	var v struct {
+		Shop struct {
+			Name string
+		}
+	}
+	t1 := time.Now()
+	err := c.do(context.Background(), QueryOperation, "{shop{name}}", nil, &v)
+	if err != nil {
+		t.Fatalf("expected no error without retries configured, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %v", calls)
+	}
+	if elapsed := time.Since(t1); elapsed < 100*time.Millisecond {
+		t.Errorf("expected to wait for the bucket to refill, waited %v", elapsed)
+	}
+	if v.Shop.Name != "kyle" {
+		t.Errorf("unexpected data %+v", v)
+	}
+}
+
+func TestDoThrottleWaitsDontSpendRetries(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"errors":[{"message":"Throttled","extensions":{"code":"THROTTLED"}}],` +
+				`"extensions":{"cost":{"requestedQueryCost":100,"actualQueryCost":null,` +
+				`"throttleStatus":{"maximumAvailable":2000.0,"currentlyAvailable":1900,"restoreRate":1000.0}}}}`))
+		case 2, 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data":{"shop":{"name":"kyle"}}}`))
+		}
+	}))
+	defer server.Close()
+
+	var policyAttempts []int
+	c := NewClient(server.URL, server.Client())
+	c.SetRetryPolicy(retryPolicyFunc(func(err error, attempts int, elapsed time.Duration) (time.Duration, bool) {
+		policyAttempts = append(policyAttempts, attempts)
+		_, retry := linearRetry{retries: 2}.Retry(err, attempts, elapsed)
+		return time.Millisecond, retry
+	}))
+	var v interface{}
+	err := c.do(context.Background(), QueryOperation, "{shop{name}}", nil, &v)
+	if !_errors.Is(err, ErrServiceUnavailable) {
+		t.Fatalf("expected (%v), got (%v)", ErrServiceUnavailable, err)
+	}
+	// The throttle wait, then the two attempts allowed by the policy.
+	if calls != 3 {
+		t.Errorf("expected (%v), got (%v)", 3, calls)
+	}
+	if len(policyAttempts) != 2 || policyAttempts[0] != 1 || policyAttempts[1] != 2 {
+		t.Errorf("expected (%v), got (%v)", []int{1, 2}, policyAttempts)
+	}
+	if !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Errorf("expected (%v), got (%v)", "after 2 attempts", err)
+	}
+}
+
+type retryPolicyFunc func(err error, attempts int, elapsed time.Duration) (time.Duration, bool)
+
+func (f retryPolicyFunc) Retry(err error, attempts int, elapsed time.Duration) (time.Duration, bool) {
+	return f(err, attempts, elapsed)
+}
+
+func TestDoDoesNotRetryMaxCostExceeded(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"errors":[{"message":"Query cost is 2500, which exceeds the single query max cost limit (1000).",` +
+			`"extensions":{"code":"MAX_COST_EXCEEDED","cost":2500,"maxCost":1000}}]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	c.SetRetries(3)
+	var v interface{}
+	err := c.do(context.Background(), QueryOperation, "{products{id}}", nil, &v)
+	if !_errors.Is(err, ErrMaxCostExceeded) {
+		t.Errorf("expected ErrMaxCostExceeded, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %v", calls)
+	}
+}
+
+func TestRateLimitStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"shop":{"name":"kyle"}},` +
+			`"extensions":{"cost":{"requestedQueryCost":12,"actualQueryCost":3,` +
+			`"throttleStatus":{"maximumAvailable":2000.0,"currentlyAvailable":1997,"restoreRate":100.0}}}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	if c.RateLimitStatus() != nil {
+		t.Fatal("expected no status before the first call")
+	}
+	var v interface{}
+	err := c.do(context.Background(), QueryOperation, "{shop{name}}", nil, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.RateLimitStatus()
+	actual := 3
+	want := &QueryCost{
+		RequestedQueryCost: 12,
+		ActualQueryCost:    &actual,
+		ThrottleStatus: ThrottleStatus{
+			MaximumAvailable:   2000,
+			CurrentlyAvailable: 1997,
+			RestoreRate:        100,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected (%+v), got (%+v)", want, got)
+	}
+}
+
+func TestDoHonorsRetryAfter(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0.2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"shop":{"name":"kyle"}}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	c.SetRetries(2)
+	var v interface{}
+	t1 := time.Now()
+	err := c.do(context.Background(), QueryOperation, "{shop{name}}", nil, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(t1)
+	if elapsed < 200*time.Millisecond || elapsed > time.Second {
+		t.Errorf("expected to wait for Retry-After, waited %v", elapsed)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %v", calls)
+	}
+}
+
+func TestTooManyRequestsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	var v interface{}
+	err := c.do(context.Background(), QueryOperation, "{shop{name}}", nil, &v)
+	if !_errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("expected ErrTooManyRequests, got %v", err)
+	}
+	var tooManyErr *TooManyRequestsError
+	if !_errors.As(err, &tooManyErr) || tooManyErr.RetryAfter != 3*time.Second {
+		t.Errorf("expected Retry-After of 3s, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{in: "", want: 0},
+		{in: "2", want: 2 * time.Second},
+		{in: "1.5", want: 1500 * time.Millisecond},
+		{in: "-1", want: 0},
+		{in: "Tue, 01 Oct 2024 12:00:05 GMT", want: 5 * time.Second},
+		{in: "Tue, 01 Oct 2024 11:59:00 GMT", want: 0},
+		{in: "soon", want: 0},
+	}
+	for _, tc := range tests {
+		if got := parseRetryAfter(tc.in, now); got != tc.want {
+			t.Errorf("parseRetryAfter(%q): expected (%v), got (%v)", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestDoReturnsAllErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"a":null,"b":null},"errors":[` +
+			`{"message":"Access denied for a field.","locations":[{"line":1,"column":2}],"path":["a"],` +
+			`"extensions":{"code":"ACCESS_DENIED","documentation":"https://shopify.dev/api/usage/access-scopes"}},` +
+			`{"message":"Invalid id","path":["b",0,"id"]}]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	var v interface{}
+	err := c.do(context.Background(), QueryOperation, "{a,b}", nil, &v)
+	var gqlErrs Errors
+	if !_errors.As(err, &gqlErrs) {
+		t.Fatalf("expected Errors, got %T", err)
+	}
+	want := Errors{
+		{
+			Message:    "Access denied for a field.",
+			Locations:  []Location{{Line: 1, Column: 2}},
+			Path:       []any{"a"},
+			Extensions: map[string]any{"code": "ACCESS_DENIED", "documentation": "https://shopify.dev/api/usage/access-scopes"},
+		},
+		{
+			Message: "Invalid id",
+			Path:    []any{"b", float64(0), "id"},
+		},
+	}
+	if !reflect.DeepEqual(gqlErrs, want) {
+		t.Errorf("expected (%+v), got (%+v)", want, gqlErrs)
+	}
+	if !gqlErrs.HasCode(AccessDenied) || gqlErrs.HasCode(Throttled) {
+		t.Error("unexpected HasCode result")
+	}
+	if !HasErrorCode(err, AccessDenied) {
+		t.Error("expected HasErrorCode to find the code through wrapping")
+	}
+	if want := "Access denied for a field.; Invalid id"; gqlErrs.Error() != want {
+		t.Errorf("expected (%v), got (%v)", want, gqlErrs.Error())
+	}
+}
+
+func TestDoReturnsPartialData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"nodes":[{"id":"gid://shopify/Product/1"},null]},"errors":[` +
+			`{"message":"Access denied for nodes field.","path":["nodes",1],"extensions":{"code":"ACCESS_DENIED"}}]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	c.SetRetries(3)
+	var v struct {
+		Nodes []*struct {
+			ID string
+		}
+	}
+	err := c.do(context.Background(), QueryOperation, `{nodes(ids:["gid://shopify/Product/1","gid://shopify/Order/1"]){id}}`, nil, &v)
+	var partialErr *PartialDataError
+	if !_errors.As(err, &partialErr) {
+		t.Fatalf("expected PartialDataError, got %v", err)
+	}
+	if len(v.Nodes) != 2 || v.Nodes[0] == nil || v.Nodes[0].ID != "gid://shopify/Product/1" || v.Nodes[1] != nil {
+		t.Errorf("expected partial data to be decoded, got %+v", v)
+	}
+	if len(partialErr.Errors.ForPath("nodes", 1)) != 1 || len(partialErr.Errors.ForPath("nodes", 0)) != 0 {
+		t.Errorf("unexpected errors for path: %+v", partialErr.Errors)
+	}
+	if !HasErrorCode(err, AccessDenied) {
+		t.Error("expected HasErrorCode to see through PartialDataError")
+	}
+}
+
+func TestQueryDecodesInlineFragments(t *testing.T) {
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			Query string `json:"query"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		gotQuery = in.Query
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"files":{"nodes":[` +
+			`{"id":"gid://shopify/MediaImage/1","image":{"url":"https://cdn.shopify.com/1.png"}},` +
+			`{"id":"gid://shopify/Video/2","duration":42}]}}}`))
+	}))
+	defer server.Close()
+
+	type node struct {
+		ID         string
+		MediaImage struct {
+			Image struct {
+				URL string
+			}
+		} `graphql:"... on MediaImage"`
+		Video struct {
+			Duration int
+		} `graphql:"... on Video"`
+	}
+	var q struct {
+		Files struct {
+			Nodes []node
+		} `graphql:"files(first: 2)"`
+	}
+	c := NewClient(server.URL, server.Client())
+	err := c.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{files(first: 2){nodes{id,... on MediaImage{image{url}},... on Video{duration}}}}"; gotQuery != want {
+		t.Errorf("expected (%v), got (%v)", want, gotQuery)
+	}
+	if len(q.Files.Nodes) != 2 {
+		t.Fatalf("expected (2) nodes, got (%v)", len(q.Files.Nodes))
+	}
+	if got := q.Files.Nodes[0]; got.ID != "gid://shopify/MediaImage/1" || got.MediaImage.Image.URL != "https://cdn.shopify.com/1.png" {
+		t.Errorf("unexpected media image %+v", got)
+	}
+	if got := q.Files.Nodes[1]; got.ID != "gid://shopify/Video/2" || got.Video.Duration != 42 {
+		t.Errorf("unexpected video %+v", got)
+	}
+}
+
+func TestVarMarshalsAsValue(t *testing.T) {
+	var gotVariables string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			Variables json.RawMessage `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		gotVariables = string(in.Variables)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"shop":{"name":"kyle"}}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	var v interface{}
+	err := c.QueryString(context.Background(), "query($q:String!){shop{name}}", map[string]interface{}{"q": Var("title:foo", "String!")}, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"q":"title:foo"}`; gotVariables != want {
+		t.Errorf("expected (%v), got (%v)", want, gotVariables)
+	}
+}
@@ -1,8 +1,11 @@
 package jsonutil_test
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"net/http"
 	"reflect"
 	"strings"
 	"testing"
@@ -125,3 +128,58 @@ func BenchmarkJSONTokenize(b *testing.B) {
 		}
 	}
 }
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type benchmarkViewerQuery struct {
+	Viewer struct {
+		Login     graphql.String
+		CreatedAt time.Time
+	} `graphql:"viewer(first: $first)"`
+}
+
+// BenchmarkClientQuery measures a whole struct-based query against a canned response:
+// building the query, which is cached after the first call, sending it and decoding the data.
+func BenchmarkClientQuery(b *testing.B) {
+	benchmarkClientQuery(b, func(int) interface{} { return &benchmarkViewerQuery{} })
+}
+
+// BenchmarkClientQueryUncached is BenchmarkClientQuery with a new query type every iteration,
+// so the query is built every time.
+func BenchmarkClientQueryUncached(b *testing.B) {
+	viewer, _ := reflect.TypeOf(benchmarkViewerQuery{}).FieldByName("Viewer")
+	types := make([]reflect.Type, b.N)
+	for i := range types {
+		// Types are cached by the query cache, so they must differ from the ones of previous runs too.
+		viewer.Tag = reflect.StructTag(fmt.Sprintf(`graphql:"viewer(first: $first)" benchmark:"%d-%d"`, b.N, i))
+		types[i] = reflect.StructOf([]reflect.StructField{viewer})
+	}
+	benchmarkClientQuery(b, func(i int) interface{} { return reflect.New(types[i]).Interface() })
+}
+
+// benchmarkClientQuery runs the query returned by newQuery for every iteration.
+func benchmarkClientQuery(b *testing.B, newQuery func(i int) interface{}) {
+	const body = `{"data":{"viewer":{"login":"shurcooL-test","createdAt":"2017-06-29T04:12:01Z"}}}`
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	})}
+	client := graphql.NewClient("https://example.myshopify.com/admin/api/graphql.json", httpClient)
+	client.SetTracer(nil)
+	variables := map[string]interface{}{"first": graphql.Int(1)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := client.Query(context.Background(), newQuery(i), variables)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -37,12 +37,24 @@ func UnmarshalGraphQL(data []byte, v any) error {
 	}
 }
 
+// Tokenizer is a source of JSON tokens, such as a *json.Decoder.
+type Tokenizer interface {
+	Token() (json.Token, error)
+}
+
+// DecodeGraphQL reads the next JSON value from tokenizer and stores it in the
+// GraphQL query data structure pointed to by v, leaving any following tokens unread.
+// It lets a GraphQL response be decoded in a single pass, e.g. for its "data" field only.
+//
+// Numbers may be tokenized as float64 or json.Number.
+func DecodeGraphQL(tokenizer Tokenizer, v any) error {
+	return (&decoder{tokenizer: tokenizer}).Decode(v)
+}
+
 // decoder is a JSON decoder that performs custom unmarshaling behavior
 // for GraphQL query data structures. It's implemented on top of a JSON tokenizer.
 type decoder struct {
-	tokenizer interface {
-		Token() (json.Token, error)
-	}
+	tokenizer Tokenizer
 
 	// Stack of what part of input JSON we're in the middle of - objects, arrays.
 	parseState []json.Delim
@@ -139,7 +151,7 @@ func (d *decoder) decode() error {
 		}
 
 		switch tok := tok.(type) {
-		case string, json.Number, bool, nil:
+		case string, json.Number, float64, bool, nil:
 			// Value.
 
 			for i := range d.vs {
@@ -1,7 +1,9 @@
 package jsonutil_test
 
 import (
+	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -393,3 +395,35 @@ func TestUnmarshalGraphQL_arrayInsideInlineFragment(t *testing.T) {
 		t.Error("not equal")
 	}
 }
+
+func TestDecodeGraphQL(t *testing.T) {
+	type query struct {
+		Me struct {
+			Name   graphql.String
+			Height graphql.Float
+			Age    graphql.Int
+		}
+	}
+	// Decode only the value of "data", without json.Decoder.UseNumber.
+	dec := json.NewDecoder(strings.NewReader(`{"data":{"me":{"name":"Luke Skywalker","height":1.72,"age":19}},"extensions":{}}`))
+	for i := 0; i < 2; i++ {
+		if _, err := dec.Token(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got query
+	err := jsonutil.DecodeGraphQL(dec, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want query
+	want.Me.Name = "Luke Skywalker"
+	want.Me.Height = 1.72
+	want.Me.Age = 19
+	if !reflect.DeepEqual(got, want) {
+		t.Error("not equal")
+	}
+	if tok, err := dec.Token(); err != nil || tok != "extensions" {
+		t.Errorf("expected the following tokens to be left unread, got (%v, %v)", tok, err)
+	}
+}
@@ -0,0 +1,101 @@
+package graphql
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	requestIDHeader        = "X-Request-Id"
+	deprecatedReasonHeader = "X-Shopify-API-Deprecated-Reason"
+)
+
+// CallMetadata describes the response to a single call of the Client, see WithCallMetadata.
+type CallMetadata struct {
+	// RequestID is the X-Request-Id Shopify assigned to the last attempt.
+	RequestID string
+	// StatusCode is the HTTP status of the last attempt, 0 if no response was received.
+	StatusCode int
+	// Header holds the response headers of the last attempt.
+	Header http.Header
+	// Cost is the "extensions.cost" of the last attempt, nil if Shopify didn't send it.
+	Cost *QueryCost
+	// Attempts is the number of times the operation was sent.
+	Attempts int
+	// DeprecatedReason is the X-Shopify-API-Deprecated-Reason header, set when the operation used deprecated fields.
+	DeprecatedReason string
+}
+
+type callMetadataKey struct{}
+
+// WithCallMetadata returns a context that makes the Client fill md with the metadata of the calls made with it.
+// When the context is used for several calls, md describes the last one to complete.
+func WithCallMetadata(ctx context.Context, md *CallMetadata) context.Context {
+	return context.WithValue(ctx, callMetadataKey{}, md)
+}
+
+func callMetadata(ctx context.Context) *CallMetadata {
+	md, _ := ctx.Value(callMetadataKey{}).(*CallMetadata)
+	return md
+}
+
+// Deprecation is reported when Shopify flags an operation as using deprecated API features.
+type Deprecation struct {
+	ShopDomain    string
+	OperationName string
+	Query         string
+	// Reason is the value of the X-Shopify-API-Deprecated-Reason header, usually a link to the changelog.
+	Reason    string
+	RequestID string
+}
+
+// DeprecationHandler is called for every operation Shopify flags as deprecated. It must be safe for concurrent use.
+type DeprecationHandler func(ctx context.Context, d Deprecation)
+
+// observe reports the response of req to the call metadata of ctx and the deprecation handler,
+// and annotates err with the request ID.
+func (c *Client) observe(ctx context.Context, req *Request, resp *Response, err error) error {
+	if resp == nil || resp.HTTPResponse == nil {
+		return err
+	}
+	header := resp.HTTPResponse.Header
+	requestID := header.Get(requestIDHeader)
+	deprecatedReason := header.Get(deprecatedReasonHeader)
+	if md := callMetadata(ctx); md != nil {
+		*md = CallMetadata{
+			RequestID:        requestID,
+			StatusCode:       resp.HTTPResponse.StatusCode,
+			Header:           header,
+			Cost:             resp.Cost,
+			Attempts:         resp.Attempts,
+			DeprecatedReason: deprecatedReason,
+		}
+	}
+	if deprecatedReason != "" && c.onDeprecation != nil {
+		c.onDeprecation(ctx, Deprecation{
+			ShopDomain:    c.shopDomain,
+			OperationName: req.OperationName,
+			Query:         req.Query,
+			Reason:        deprecatedReason,
+			RequestID:     requestID,
+		})
+	}
+	if err != nil && requestID != "" {
+		return &RequestIDError{RequestID: requestID, Err: err}
+	}
+	return err
+}
+
+type apiVersionKey struct{}
+
+// WithAPIVersion returns a context that makes the calls made with it use the given API version, e.g. "2025-01",
+// instead of the client's. They share the client's transport, auth and rate-limit state.
+// The client must have been configured with SetVersionURL, which graphqlclient.NewClient does.
+func WithAPIVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, apiVersionKey{}, version)
+}
+
+func apiVersion(ctx context.Context) string {
+	version, _ := ctx.Value(apiVersionKey{}).(string)
+	return version
+}
@@ -0,0 +1,78 @@
+package graphql
+
+import (
+	"context"
+	_errors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDoRecordsRequestIDOnErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "abc-123")
+		w.WriteHeader(http.StatusLocked)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	var v interface{}
+	err := c.QueryString(context.Background(), "query { shop { name } }", nil, &v)
+	if !_errors.Is(err, ErrLocked) {
+		t.Fatalf("expected (%v), got (%v)", ErrLocked, err)
+	}
+	if id := RequestID(err); id != "abc-123" {
+		t.Errorf("expected (abc-123), got (%v)", id)
+	}
+	if !strings.Contains(err.Error(), "abc-123") {
+		t.Errorf("expected the request id in %q", err.Error())
+	}
+}
+
+func TestWithCallMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "abc-123")
+		w.Header().Set("X-Shopify-API-Deprecated-Reason", "https://shopify.dev/changelog/deprecated")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"shop":{"name":"kyle"}},` +
+			`"extensions":{"cost":{"requestedQueryCost":4,"actualQueryCost":2,` +
+			`"throttleStatus":{"maximumAvailable":2000.0,"currentlyAvailable":1998,"restoreRate":100.0}}}}`))
+	}))
+	defer server.Close()
+
+	var deprecations []Deprecation
+	c := NewClient(server.URL, server.Client())
+	c.SetDeprecationHandler(func(ctx context.Context, d Deprecation) {
+		deprecations = append(deprecations, d)
+	})
+	var md CallMetadata
+	var v interface{}
+	err := c.QueryString(WithCallMetadata(context.Background(), &md), "query { shop { name } }", nil, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.RequestID != "abc-123" || md.StatusCode != http.StatusOK || md.Attempts != 1 ||
+		md.Cost == nil || md.Cost.RequestedQueryCost != 4 {
+		t.Errorf("unexpected metadata %+v", md)
+	}
+	if md.DeprecatedReason != "https://shopify.dev/changelog/deprecated" {
+		t.Errorf("unexpected deprecated reason (%v)", md.DeprecatedReason)
+	}
+	if len(deprecations) != 1 {
+		t.Fatalf("expected (1) deprecation, got (%v)", len(deprecations))
+	}
+	if d := deprecations[0]; d.OperationName != "shop" || d.RequestID != "abc-123" ||
+		d.Reason != "https://shopify.dev/changelog/deprecated" {
+		t.Errorf("unexpected deprecation %+v", d)
+	}
+}
+
+func TestWithAPIVersionRequiresVersionURL(t *testing.T) {
+	c := NewClient("https://example.myshopify.com/admin/api/graphql.json", nil)
+	var v interface{}
+	err := c.QueryString(WithAPIVersion(context.Background(), "2025-01"), "{shop{name}}", nil, &v)
+	if !_errors.Is(err, ErrVersionOverrideUnsupported) {
+		t.Errorf("expected (%v), got (%v)", ErrVersionOverrideUnsupported, err)
+	}
+}
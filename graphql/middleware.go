@@ -0,0 +1,167 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gempages/go-shopify-graphql/metrics"
+	"github.com/gempages/go-shopify-graphql/tracer"
+)
+
+// Request is a single GraphQL operation going through the middleware chain.
+type Request struct {
+	Operation OperationType
+	// OperationName is the comma-separated list of the root fields selected by the operation, e.g. "products".
+	OperationName string
+	// URL is the endpoint the operation is sent to, which depends on the API version used for the call.
+	URL       string
+	Query     string
+	Variables map[string]interface{}
+	// Header is sent with every attempt of the operation. Middlewares may add to it.
+	Header http.Header
+	// Result is the value the response data is decoded into.
+	Result interface{}
+
+	// graphQLResult is set when Result is the struct the query was built from, so it is decoded
+	// with jsonutil.UnmarshalGraphQL to fill inline fragments and embedded structs.
+	graphQLResult bool
+}
+
+// Response describes the outcome of a GraphQL operation, as seen by the middleware chain.
+type Response struct {
+	// HTTPResponse is the response of the last attempt. Its body has already been consumed.
+	// It is nil when no response was received, e.g. on connection errors.
+	HTTPResponse *http.Response
+	// Cost is the "extensions.cost" of the last attempt, nil if Shopify didn't send it.
+	Cost *QueryCost
+	// Attempts is the number of times the operation was sent.
+	Attempts int
+	// ThrottleWaits is how many of the attempts followed a wait for the cost bucket after being throttled.
+	ThrottleWaits int
+}
+
+// Handler executes a GraphQL operation. The returned Response may be nil, or non-nil along with an error.
+type Handler func(ctx context.Context, req *Request) (*Response, error)
+
+// Middleware wraps a Handler to observe or alter operations, e.g. for logging, metrics or header injection.
+type Middleware func(next Handler) Handler
+
+// tracing reports every operation as a span of the client's tracer.
+func (c *Client) tracing(next Handler) Handler {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		ctx, span := c.tracer.Start(ctx, "shopify_graphql.send", req.OperationName)
+		span.SetAttribute(tracer.AttributeOperationName, req.OperationName)
+		span.SetAttribute(tracer.AttributeShopDomain, c.shopDomain)
+		span.SetAttribute(tracer.AttributeURL, req.URL)
+		span.SetAttribute(tracer.AttributeQuery, req.Query)
+		span.SetAttribute(tracer.AttributeVariables, req.Variables)
+		resp, err := next(ctx, req)
+		if resp != nil && resp.HTTPResponse != nil {
+			span.SetAttribute(tracer.AttributeRequestID, resp.HTTPResponse.Header.Get(requestIDHeader))
+		}
+		if resp != nil && resp.Cost != nil {
+			span.SetAttribute(tracer.AttributeRequestedQueryCost, resp.Cost.RequestedQueryCost)
+			if resp.Cost.ActualQueryCost != nil {
+				span.SetAttribute(tracer.AttributeActualQueryCost, *resp.Cost.ActualQueryCost)
+			}
+		}
+		span.Finish(err)
+		return resp, err
+	}
+}
+
+// metering reports every operation to the client's metrics recorder.
+func (c *Client) metering(next Handler) Handler {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		start := time.Now()
+		resp, err := next(ctx, req)
+		op := metrics.Operation{
+			ShopDomain:    c.shopDomain,
+			OperationName: req.OperationName,
+			Mutation:      req.Operation == MutationOperation,
+			Duration:      time.Since(start),
+			Err:           err,
+		}
+		if resp != nil {
+			if resp.HTTPResponse != nil {
+				op.StatusCode = resp.HTTPResponse.StatusCode
+			}
+			if resp.Attempts > 0 {
+				op.Retries = resp.Attempts - 1 - resp.ThrottleWaits
+			}
+			op.ThrottleWaits = resp.ThrottleWaits
+			if resp.Cost != nil {
+				op.RequestedQueryCost = resp.Cost.RequestedQueryCost
+				if resp.Cost.ActualQueryCost != nil {
+					op.ActualQueryCost = *resp.Cost.ActualQueryCost
+				}
+			}
+		}
+		c.metrics.RecordOperation(ctx, op)
+		return resp, err
+	}
+}
+
+// retry sends the operation until it succeeds or the retry policy gives up, keeping the cost bucket
+// up to date with every response. Throttled operations wait for the bucket without spending a retry.
+// Mutations are only retried when the failure provably happened before Shopify ran them,
+// unless ctx was marked with WithIdempotentMutation.
+func (c *Client) retry(next Handler) Handler {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		start := time.Now()
+		attempts := 0
+		throttleWaits := 0
+		bucket := bucketFor(c.shopDomain)
+		for {
+			attempts++
+			err := bucket.wait(ctx, c.estimateCost(req.Query))
+			if err != nil {
+				return nil, err
+			}
+			resp, err := next(ctx, req)
+			if resp == nil {
+				resp = &Response{}
+			}
+			resp.Attempts = attempts
+			resp.ThrottleWaits = throttleWaits
+			if cost := resp.Cost; cost != nil {
+				bucket.update(cost.ThrottleStatus)
+				c.costs.set(req.Query, cost.RequestedQueryCost)
+				c.lastCost.Store(cost)
+			}
+			if err == nil {
+				return resp, nil
+			}
+			// The bucket now knows exactly how long until there is room for this query,
+			// so wait for it on the next iteration instead of spending a retry.
+			if cost := resp.Cost; isThrottledError(err) && cost != nil && throttleWaits < maxThrottleWaits &&
+				float64(cost.RequestedQueryCost) <= cost.ThrottleStatus.MaximumAvailable {
+				throttleWaits++
+				continue
+			}
+			// Throttle waits don't count as attempts for the retry policy.
+			retryAttempts := attempts - throttleWaits
+			if req.Operation == MutationOperation && !isIdempotentMutation(ctx) && !isUnsentError(err) {
+				return resp, attemptsError(req.Operation, retryAttempts, err)
+			}
+			delay, retry := c.retryPolicy.Retry(err, retryAttempts, time.Since(start))
+			if !retry {
+				return resp, attemptsError(req.Operation, retryAttempts, err)
+			}
+			if sleepErr := Sleep(ctx, delay); sleepErr != nil {
+				return resp, fmt.Errorf("%w: %w", sleepErr, attemptsError(req.Operation, retryAttempts, err))
+			}
+		}
+	}
+}
+
+// attemptsError wraps the last error of an operation that won't be attempted again.
+// Mutations failing in a way that doesn't tell whether Shopify applied them are marked with ErrOutcomeUnknown.
+func attemptsError(op OperationType, attempts int, err error) error {
+	if op == MutationOperation && isOutcomeUnknownError(err) {
+		return fmt.Errorf("after %v attempts: %w: %w", attempts, ErrOutcomeUnknown, err)
+	}
+	return fmt.Errorf("after %v attempts: %w", attempts, err)
+}
@@ -0,0 +1,192 @@
+package graphql
+
+import (
+	"context"
+	_errors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gempages/go-shopify-graphql/metrics"
+	"github.com/gempages/go-shopify-graphql/tracer"
+)
+
+func TestClientUse(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if got := r.Header.Get("X-Test"); got != "yes" {
+			t.Errorf("expected header injected by middleware, got (%v)", got)
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"shop":{"name":"kyle"}},` +
+			`"extensions":{"cost":{"requestedQueryCost":1,"actualQueryCost":1,` +
+			`"throttleStatus":{"maximumAvailable":2000.0,"currentlyAvailable":1999,"restoreRate":100.0}}}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	c.SetRetries(2)
+	var (
+		order   []string
+		seenReq *Request
+		seenRes *Response
+		seenErr error
+	)
+	c.Use(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			order = append(order, "first")
+			req.Header.Set("X-Test", "yes")
+			return next(ctx, req)
+		}
+	}, func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			order = append(order, "second")
+			resp, err := next(ctx, req)
+			seenReq, seenRes, seenErr = req, resp, err
+			return resp, err
+		}
+	})
+
+	var v struct {
+		Shop struct {
+			Name string
+		}
+	}
+	err := c.QueryString(context.Background(), "query { shop { name } }", map[string]interface{}{"a": 1}, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("expected middlewares to run once each in order, got %v", order)
+	}
+	if seenReq.OperationName != "shop" || seenReq.Operation != QueryOperation || seenReq.Variables["a"] != 1 {
+		t.Errorf("unexpected request %+v", seenReq)
+	}
+	if seenErr != nil || seenRes == nil || seenRes.Attempts != 2 || seenRes.HTTPResponse.StatusCode != http.StatusOK {
+		t.Errorf("unexpected response %+v, error %v", seenRes, seenErr)
+	}
+	if seenRes.Cost == nil || seenRes.Cost.RequestedQueryCost != 1 {
+		t.Errorf("expected cost in response, got %+v", seenRes.Cost)
+	}
+	if v.Shop.Name != "kyle" {
+		t.Errorf("unexpected data %+v", v)
+	}
+}
+
+func TestClientUseCanShortCircuit(t *testing.T) {
+	c := NewClient("http://127.0.0.1:0", nil)
+	errStub := _errors.New("stubbed")
+	c.Use(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			return nil, errStub
+		}
+	})
+	var v interface{}
+	err := c.MutateString(context.Background(), "mutation { a }", nil, &v)
+	if !_errors.Is(err, errStub) {
+		t.Errorf("expected stubbed error, got %v", err)
+	}
+}
+
+type recordingTracer struct {
+	operation   string
+	description string
+	attributes  map[string]any
+	err         error
+	finished    bool
+}
+
+func (r *recordingTracer) Start(ctx context.Context, operation string, description string) (context.Context, tracer.Span) {
+	r.operation, r.description = operation, description
+	r.attributes = make(map[string]any)
+	return ctx, r
+}
+
+func (r *recordingTracer) SetAttribute(key string, value any) {
+	r.attributes[key] = value
+}
+
+func (r *recordingTracer) Finish(err error) {
+	r.err, r.finished = err, true
+}
+
+func TestClientSetTracer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"shop":{"name":"kyle"}},` +
+			`"extensions":{"cost":{"requestedQueryCost":4,"actualQueryCost":2,` +
+			`"throttleStatus":{"maximumAvailable":2000.0,"currentlyAvailable":1998,"restoreRate":100.0}}}}`))
+	}))
+	defer server.Close()
+
+	rec := &recordingTracer{}
+	c := NewClient(server.URL, server.Client())
+	c.SetTracer(rec)
+	var v interface{}
+	err := c.QueryString(context.Background(), "query { shop { name } }", nil, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.operation != "shopify_graphql.send" || rec.description != "shop" || !rec.finished || rec.err != nil {
+		t.Errorf("unexpected span %+v", rec)
+	}
+	if rec.attributes[tracer.AttributeOperationName] != "shop" ||
+		rec.attributes[tracer.AttributeShopDomain] != strings.TrimPrefix(server.URL, "http://") ||
+		rec.attributes[tracer.AttributeRequestedQueryCost] != 4 ||
+		rec.attributes[tracer.AttributeActualQueryCost] != 2 {
+		t.Errorf("unexpected attributes %+v", rec.attributes)
+	}
+}
+
+func TestClientSetMetrics(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"errors":[{"message":"Throttled","extensions":{"code":"THROTTLED"}}],` +
+				`"extensions":{"cost":{"requestedQueryCost":10,"actualQueryCost":null,` +
+				`"throttleStatus":{"maximumAvailable":2000.0,"currentlyAvailable":0,"restoreRate":1000.0}}}}`))
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"data":{"shop":{"name":"kyle"}},` +
+				`"extensions":{"cost":{"requestedQueryCost":10,"actualQueryCost":2,` +
+				`"throttleStatus":{"maximumAvailable":2000.0,"currentlyAvailable":1998,"restoreRate":1000.0}}}}`))
+		}
+	}))
+	defer server.Close()
+
+	rec := metrics.NewInMemory()
+	c := NewClient(server.URL, server.Client())
+	c.SetMetrics(rec)
+	c.SetRetryPolicy(&ExponentialBackoff{MaxAttempts: 5, InitialInterval: time.Millisecond})
+	var v interface{}
+	err := c.QueryString(context.Background(), "query { shop { name } }", nil, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := rec.Operations()
+	if len(ops) != 1 {
+		t.Fatalf("expected (1) operation, got (%v)", len(ops))
+	}
+	op := ops[0]
+	if op.ShopDomain != strings.TrimPrefix(server.URL, "http://") || op.OperationName != "shop" || op.Mutation {
+		t.Errorf("unexpected operation %+v", op)
+	}
+	if op.StatusCode != http.StatusOK || op.Retries != 1 || op.ThrottleWaits != 1 {
+		t.Errorf("expected status 200 with 1 retry and 1 throttle wait, got %+v", op)
+	}
+	if op.RequestedQueryCost != 10 || op.ActualQueryCost != 2 || op.Duration <= 0 || op.Err != nil {
+		t.Errorf("unexpected operation %+v", op)
+	}
+}
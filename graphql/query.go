@@ -6,24 +6,98 @@ import (
 	"io"
 	"reflect"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/gempages/go-shopify-graphql/graphql/ident"
 )
 
+// queryCache holds the operations built by constructQuery and constructMutation,
+// so the struct is only walked with reflection once per type and variables signature.
+var queryCache sync.Map // queryCacheKey -> string
+
+type queryCacheKey struct {
+	mutation  bool
+	t         reflect.Type
+	variables string
+	// generation is the variableTypesGeneration the operation was built with, so operations built
+	// before a RegisterVariableType call aren't used after it.
+	generation uint64
+}
+
+var (
+	// variableTypeIDs numbers the Go types of variables, so variablesKey can tell them apart by a short ID.
+	variableTypeIDs    sync.Map // reflect.Type -> uint64
+	lastVariableTypeID atomic.Uint64
+)
+
 func constructQuery(v interface{}, variables map[string]interface{}) string {
-	query := query(v)
-	if len(variables) > 0 {
-		return "query(" + queryArguments(variables) + ")" + query
-	}
-	return query
+	return constructOperation(false, v, variables)
 }
 
 func constructMutation(v interface{}, variables map[string]interface{}) string {
-	query := query(v)
-	if len(variables) > 0 {
-		return "mutation(" + queryArguments(variables) + ")" + query
+	return constructOperation(true, v, variables)
+}
+
+func constructOperation(mutation bool, v interface{}, variables map[string]interface{}) string {
+	key := queryCacheKey{
+		mutation:   mutation,
+		t:          reflect.TypeOf(v),
+		variables:  variablesKey(variables),
+		generation: variableTypesGeneration.Load(),
 	}
-	return "mutation" + query
+	if op, ok := queryCache.Load(key); ok {
+		return op.(string)
+	}
+	op := query(v)
+	arguments := queryArguments(variables)
+	switch {
+	case arguments != "" && mutation:
+		op = "mutation(" + arguments + ")" + op
+	case arguments != "":
+		op = "query(" + arguments + ")" + op
+	case mutation:
+		op = "mutation" + op
+	}
+	queryCache.Store(key, op)
+	return op
+}
+
+// variablesKey returns the sorted names of variables with the Go types of their values, or the types
+// of Variable values, which is all queryArguments depends on. Unlike queryArguments, it doesn't walk the types.
+//
+// E.g., map[string]interface{}{"a": Int(123), "b": Var(1, "Int")} -> "$a#1$b:Int", 1 being the ID of Int.
+func variablesKey(variables map[string]interface{}) string {
+	keys := make([]string, 0, len(variables))
+	for k := range variables {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf strings.Builder
+	for _, k := range keys {
+		buf.WriteString("$")
+		buf.WriteString(k)
+		if v, ok := variables[k].(Variable); ok {
+			buf.WriteString(":")
+			buf.WriteString(v.Type)
+			continue
+		}
+		buf.WriteString("#")
+		buf.WriteString(strconv.FormatUint(variableTypeID(reflect.TypeOf(variables[k])), 10))
+	}
+	return buf.String()
+}
+
+// variableTypeID returns the ID of t in variableTypeIDs, adding it if needed.
+func variableTypeID(t reflect.Type) uint64 {
+	if id, ok := variableTypeIDs.Load(t); ok {
+		return id.(uint64)
+	}
+	id, _ := variableTypeIDs.LoadOrStore(t, lastVariableTypeID.Add(1))
+	return id.(uint64)
 }
 
 // queryArguments constructs a minified arguments string for variables.
@@ -43,6 +117,10 @@ func queryArguments(variables map[string]interface{}) string {
 		io.WriteString(&buf, "$")
 		io.WriteString(&buf, k)
 		io.WriteString(&buf, ":")
+		if v, ok := variables[k].(Variable); ok {
+			io.WriteString(&buf, v.Type)
+			continue
+		}
 		writeArgumentType(&buf, reflect.TypeOf(variables[k]), true)
 		// Don't insert a comma here.
 		// Commas in GraphQL are insignificant, and we want minified output.
@@ -55,6 +133,13 @@ func queryArguments(variables map[string]interface{}) string {
 // value indicates whether t is a value (required) type or pointer (optional) type.
 // If value is true, then "!" is written at the end of t.
 func writeArgumentType(w io.Writer, t reflect.Type, value bool) {
+	if typ, ok := registeredVariableType(t); ok {
+		if !value {
+			typ = nullable(typ)
+		}
+		io.WriteString(w, typ)
+		return
+	}
 	if t.Kind() == reflect.Ptr {
 		// Pointer is an optional type, so no "!" at the end of the pointer's underlying type.
 		writeArgumentType(w, t.Elem(), false)
@@ -69,12 +154,7 @@ func writeArgumentType(w io.Writer, t reflect.Type, value bool) {
 		io.WriteString(w, "]")
 	default:
 		// Named type. E.g., "Int".
-		name := t.Name()
-		if name == "string" { // HACK: Workaround for https://github.com/shurcooL/githubv4/issues/12.
-			name = "ID"
-			// name = "String"
-		}
-		io.WriteString(w, name)
+		io.WriteString(w, t.Name())
 	}
 
 	if value {
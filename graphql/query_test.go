@@ -271,6 +271,10 @@ func TestConstructMutation(t *testing.T) {
 	}
 }
 
+func init() {
+	RegisterVariableType(NullableString{}, "String")
+}
+
 func TestQueryArguments(t *testing.T) {
 	tests := []struct {
 		in   map[string]interface{}
@@ -313,6 +317,18 @@ func TestQueryArguments(t *testing.T) {
 			in:   map[string]interface{}{"ids": &[]ID{"someID", "anotherID"}},
 			want: `$ids:[ID!]`,
 		},
+		{
+			in:   map[string]interface{}{"query": Var("title:foo", "String!"), "after": Var((*string)(nil), "String")},
+			want: `$after:String$query:String!`,
+		},
+		{
+			in:   map[string]interface{}{"input": Var(&ProductInput{}, "ProductInput!")},
+			want: `$input:ProductInput!`,
+		},
+		{
+			in:   map[string]interface{}{"title": NullableString{}, "titles": []*NullableString{}},
+			want: `$title:String$titles:[String]!`,
+		},
 	}
 	for i, tc := range tests {
 		got := queryArguments(tc.in)
@@ -324,6 +340,14 @@ func TestQueryArguments(t *testing.T) {
 
 // Custom GraphQL types for testing.
 type (
+	ProductInput struct{ Title string }
+
+	// NullableString is a string that is sent as null when not valid, like null.String.
+	NullableString struct {
+		String string
+		Valid  bool
+	}
+
 	// DateTime is an ISO-8601 encoded UTC date.
 	DateTime struct{ time.Time }
 
@@ -365,3 +389,97 @@ type AddReactionInput struct {
 	// A unique identifier for the client performing the mutation. (Optional.)
 	ClientMutationID *String `json:"clientMutationId,omitempty"`
 }
+
+func TestConstructQueryCachesPerVariablesSignature(t *testing.T) {
+	type q struct {
+		Node struct {
+			ID ID
+		} `graphql:"node(id: $id)"`
+	}
+	tests := []struct {
+		variables map[string]interface{}
+		want      string
+	}{
+		{variables: map[string]interface{}{"id": ID("1")}, want: "query($id:ID!){node(id: $id){id}}"},
+		{variables: map[string]interface{}{"id": ID("2")}, want: "query($id:ID!){node(id: $id){id}}"},
+		{variables: map[string]interface{}{"id": NewID("1")}, want: "query($id:ID){node(id: $id){id}}"},
+		{variables: map[string]interface{}{"id": Var("1", "ID")}, want: "query($id:ID){node(id: $id){id}}"},
+		{variables: map[string]interface{}{"id": Var("1", "String!")}, want: "query($id:String!){node(id: $id){id}}"},
+	}
+	for _, tt := range tests {
+		got := constructQuery(q{}, tt.variables)
+		if got != tt.want {
+			t.Errorf("expected (%v), got (%v)", tt.want, got)
+		}
+	}
+}
+
+type lateRegisteredID string
+
+func TestConstructQueryAfterRegisterVariableType(t *testing.T) {
+	type q struct {
+		Node struct {
+			ID ID
+		} `graphql:"node(id: $id)"`
+	}
+	variables := map[string]interface{}{"id": lateRegisteredID("gid://shopify/Product/1")}
+	want := "query($id:lateRegisteredID!){node(id: $id){id}}"
+	if got := constructQuery(q{}, variables); got != want {
+		t.Errorf("expected (%v), got (%v)", want, got)
+	}
+
+	RegisterVariableType(lateRegisteredID(""), "ID!")
+	want = "query($id:ID!){node(id: $id){id}}"
+	if got := constructQuery(q{}, variables); got != want {
+		t.Errorf("expected (%v), got (%v)", want, got)
+	}
+}
+
+type benchmarkProductsQuery struct {
+	Products struct {
+		Edges []struct {
+			Node struct {
+				ID       ID
+				Title    String
+				Handle   String
+				Variants struct {
+					Edges []struct {
+						Node struct {
+							ID    ID
+							Price String
+							SKU   String
+						}
+					}
+				} `graphql:"variants(first: 10)"`
+			}
+		}
+		PageInfo struct {
+			HasNextPage Boolean
+			EndCursor   String
+		}
+	} `graphql:"products(first: $first, after: $after)"`
+}
+
+var benchmarkProductsVariables = map[string]interface{}{
+	"first": Int(50),
+	"after": NewString("cursor"),
+}
+
+func BenchmarkConstructQuery(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		constructQuery(&benchmarkProductsQuery{}, benchmarkProductsVariables)
+	}
+}
+
+func BenchmarkConstructQueryUncached(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		// A new generation misses the operations cached so far, like after RegisterVariableType.
+		variableTypesGeneration.Add(1)
+		constructQuery(&benchmarkProductsQuery{}, benchmarkProductsVariables)
+	}
+	b.StopTimer()
+	queryCache.Range(func(key, _ interface{}) bool {
+		queryCache.Delete(key)
+		return true
+	})
+}
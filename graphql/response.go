@@ -0,0 +1,126 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/gempages/go-shopify-graphql/graphql/internal/jsonutil"
+)
+
+// bodyContextLength is how much of a response body is kept to give context to decoding errors.
+const bodyContextLength = 500
+
+// responseBody holds what decodeResponse read from a response, except the data which goes straight to the target.
+type responseBody struct {
+	// hasData is set when the response has non-null "data".
+	hasData    bool
+	Errors     Errors
+	Extensions struct {
+		Cost *QueryCost
+	}
+}
+
+// decodeResponse decodes a GraphQL response from r in a single pass, with "data" decoded straight into v,
+// using jsonutil.DecodeGraphQL if graphQL is set or encoding/json otherwise.
+func decodeResponse(r io.Reader, v interface{}, graphQL bool) (*responseBody, error) {
+	dec := json.NewDecoder(r)
+	if graphQL {
+		// Tokens are decoded one by one, keep numbers as json.Number so that IDs above 2^53 don't lose precision.
+		dec.UseNumber()
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object, got %v", tok)
+	}
+	body := &responseBody{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		switch key {
+		case "data":
+			body.hasData, err = decodeData(dec, v, graphQL)
+			if err != nil {
+				return nil, fmt.Errorf("unmarshal data: %w", err)
+			}
+		case "errors":
+			// Unmarshalled on its own so that numbers in extensions stay float64.
+			var errs json.RawMessage
+			if err = dec.Decode(&errs); err == nil {
+				err = json.Unmarshal(errs, &body.Errors)
+			}
+		case "extensions":
+			err = dec.Decode(&body.Extensions)
+		default:
+			err = dec.Decode(&json.RawMessage{})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = dec.Token()
+	return body, err
+}
+
+// decodeData decodes the value of "data" from dec into v, reporting whether it wasn't null.
+func decodeData(dec *json.Decoder, v interface{}, graphQL bool) (bool, error) {
+	if v == nil {
+		var data json.RawMessage
+		err := dec.Decode(&data)
+		return err == nil && !bytes.Equal(data, []byte("null")), err
+	}
+	if graphQL {
+		tok, err := dec.Token()
+		if err != nil || tok == nil {
+			return false, err
+		}
+		return true, jsonutil.DecodeGraphQL(&pushedBackToken{tok: tok, tokenizer: dec}, v)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return false, &json.InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	// Decode through a pointer to v: encoding/json sets it to nil when the data is null,
+	// and decodes into v otherwise.
+	p := reflect.New(rv.Type())
+	p.Elem().Set(rv)
+	err := dec.Decode(p.Interface())
+	return !p.Elem().IsNil(), err
+}
+
+// pushedBackToken is a jsonutil.Tokenizer returning tok before the tokens of tokenizer.
+type pushedBackToken struct {
+	tok       json.Token
+	tokenizer jsonutil.Tokenizer
+	done      bool
+}
+
+func (t *pushedBackToken) Token() (json.Token, error) {
+	if !t.done {
+		t.done = true
+		return t.tok, nil
+	}
+	return t.tokenizer.Token()
+}
+
+// headRecorder keeps the first bytes read through it, to give context to decoding errors.
+type headRecorder struct {
+	r    io.Reader
+	head bytes.Buffer
+}
+
+func (h *headRecorder) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	if room := bodyContextLength - h.head.Len(); room > 0 {
+		h.head.Write(p[:min(n, room)])
+	}
+	return n, err
+}
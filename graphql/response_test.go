@@ -0,0 +1,124 @@
+package graphql
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	_errors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		graphQL bool
+		hasData bool
+		errors  int
+	}{
+		{name: "data first", body: `{"data":{"shop":{"name":"kyle"}},"extensions":{"cost":{"requestedQueryCost":1}}}`, hasData: true},
+		{name: "errors first", body: `{"errors":[{"message":"oops","path":["shop","email"]}],"data":{"shop":{"name":"kyle"}}}`, hasData: true, errors: 1},
+		{name: "null data", body: `{"data":null,"errors":[{"message":"oops"}]}`, errors: 1},
+		{name: "no data", body: `{"errors":[{"message":"oops"}]}`, errors: 1},
+		{name: "unknown field", body: `{"data":{"shop":{"name":"kyle"}},"foo":[1,{"bar":2}]}`, hasData: true},
+		{name: "graphql data first", body: `{"data":{"shop":{"name":"kyle"}},"extensions":{"cost":{"requestedQueryCost":1}}}`, graphQL: true, hasData: true},
+		{name: "graphql null data", body: `{"data":null,"errors":[{"message":"oops"}]}`, graphQL: true, errors: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v struct {
+				Shop struct {
+					Name string
+				}
+			}
+			body, err := decodeResponse(strings.NewReader(tt.body), &v, tt.graphQL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body.hasData != tt.hasData {
+				t.Errorf("expected hasData (%v), got (%v)", tt.hasData, body.hasData)
+			}
+			if len(body.Errors) != tt.errors {
+				t.Errorf("expected (%v) errors, got (%v)", tt.errors, len(body.Errors))
+			}
+			if tt.hasData && v.Shop.Name != "kyle" {
+				t.Errorf("unexpected data %+v", v)
+			}
+		})
+	}
+}
+
+func TestDecodeResponseKeepsLargeIntegers(t *testing.T) {
+	var v struct {
+		Order struct {
+			LegacyResourceID int64
+		}
+	}
+	body := `{"data":{"order":{"legacyResourceId":9007199254740993}},"errors":[{"message":"oops","extensions":{"cost":1}}]}`
+	resp, err := decodeResponse(strings.NewReader(body), &v, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Order.LegacyResourceID != 9007199254740993 {
+		t.Errorf("expected (%v), got (%v)", int64(9007199254740993), v.Order.LegacyResourceID)
+	}
+	if cost, ok := resp.Errors[0].Extensions["cost"].(float64); !ok || cost != 1 {
+		t.Errorf("expected (%v), got (%v)", 1, resp.Errors[0].Extensions["cost"])
+	}
+}
+
+func TestDoDecompressesGzipResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("expected gzip to be accepted, got (%v)", r.Header.Get("Accept-Encoding"))
+		}
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(`{"data":{"shop":{"name":"kyle"}}}`))
+		gz.Close()
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	var v struct {
+		Shop struct {
+			Name string
+		}
+	}
+	err := c.QueryString(context.Background(), "{shop{name}}", nil, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Shop.Name != "kyle" {
+		t.Errorf("unexpected data %+v", v)
+	}
+}
+
+func TestDoReportsInvalidBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"shop":{"name":42}}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	var v struct {
+		Shop struct {
+			Name string
+		}
+	}
+	err := c.QueryString(context.Background(), "{shop{name}}", nil, &v)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	var gqlErrs Errors
+	if _errors.As(err, &gqlErrs) {
+		t.Errorf("expected a decoding error, got (%v)", err)
+	}
+}
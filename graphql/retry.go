@@ -0,0 +1,125 @@
+package graphql
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy decides whether a failed operation is attempted again and how long to wait before it.
+// Throttled responses that carry Shopify's throttle status are waited out by the client itself
+// and never reach the policy.
+type RetryPolicy interface {
+	// Retry is called after every failed attempt with the error, the number of attempts made so far,
+	// not counting the ones that waited for the throttle, and the time elapsed since the first attempt
+	// was sent. It returns the delay before the next attempt and whether there should be one at all.
+	Retry(err error, attempts int, elapsed time.Duration) (time.Duration, bool)
+}
+
+type idempotentMutationKey struct{}
+
+// WithIdempotentMutation marks the mutations run with the returned context as safe to send more than once,
+// so they are retried like queries. Without it, a mutation is only retried when it provably didn't reach Shopify.
+func WithIdempotentMutation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentMutationKey{}, true)
+}
+
+func isIdempotentMutation(ctx context.Context) bool {
+	idempotent, _ := ctx.Value(idempotentMutationKey{}).(bool)
+	return idempotent
+}
+
+// NoRetry is a RetryPolicy that never retries.
+var NoRetry RetryPolicy = noRetry{}
+
+type noRetry struct{}
+
+func (noRetry) Retry(error, int, time.Duration) (time.Duration, bool) {
+	return 0, false
+}
+
+// linearRetry is the policy installed by SetRetries: at most retries attempts,
+// waiting one more second before each of them.
+type linearRetry struct {
+	retries int
+}
+
+func (p linearRetry) Retry(err error, attempts int, _ time.Duration) (time.Duration, bool) {
+	if attempts >= p.retries || !IsRetryableError(err) {
+		return 0, false
+	}
+	if d := retryAfter(err); d > 0 {
+		return d, true
+	}
+	return time.Duration(attempts) * time.Second, true
+}
+
+// ExponentialBackoff is a RetryPolicy that doubles (or multiplies by Multiplier) the delay after every attempt,
+// randomized by Jitter. A Retry-After sent by Shopify is honored when it is longer than the computed delay.
+type ExponentialBackoff struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. Zero means unlimited.
+	MaxAttempts int
+	// InitialInterval is the delay before the second attempt. Defaults to 500ms.
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between two attempts. Zero means no cap.
+	MaxInterval time.Duration
+	// Multiplier is the factor the delay grows by after every attempt. Defaults to 2.
+	Multiplier float64
+	// Jitter randomizes every delay by ±Jitter of its value, e.g. 0.5 for a delay between 50% and 150%.
+	Jitter float64
+	// MaxElapsedTime stops retrying once this much time has passed since the first attempt. Zero means no limit.
+	MaxElapsedTime time.Duration
+	// Retryable reports whether err is worth retrying. Defaults to IsRetryableError.
+	Retryable func(err error) bool
+}
+
+// NewExponentialBackoff returns an ExponentialBackoff with sensible defaults for the Shopify API.
+func NewExponentialBackoff() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		MaxAttempts:     5,
+		InitialInterval: 500 * time.Millisecond,
+		MaxInterval:     30 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.5,
+		MaxElapsedTime:  2 * time.Minute,
+	}
+}
+
+func (p *ExponentialBackoff) Retry(err error, attempts int, elapsed time.Duration) (time.Duration, bool) {
+	if p.MaxAttempts > 0 && attempts >= p.MaxAttempts {
+		return 0, false
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryableError
+	}
+	if !retryable(err) {
+		return 0, false
+	}
+
+	initial := p.InitialInterval
+	if initial <= 0 {
+		initial = 500 * time.Millisecond
+	}
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	delay := float64(initial) * math.Pow(multiplier, float64(attempts-1))
+	if p.MaxInterval > 0 && delay > float64(p.MaxInterval) {
+		delay = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	d := time.Duration(delay)
+	if ra := retryAfter(err); ra > d {
+		d = ra
+	}
+
+	if p.MaxElapsedTime > 0 && elapsed+d > p.MaxElapsedTime {
+		return 0, false
+	}
+	return d, true
+}
@@ -0,0 +1,180 @@
+package graphql
+
+import (
+	"context"
+	_errors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	p := &ExponentialBackoff{
+		MaxAttempts:     4,
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     300 * time.Millisecond,
+		Multiplier:      2,
+	}
+	tests := []struct {
+		attempts  int
+		wantDelay time.Duration
+		wantRetry bool
+	}{
+		{attempts: 1, wantDelay: 100 * time.Millisecond, wantRetry: true},
+		{attempts: 2, wantDelay: 200 * time.Millisecond, wantRetry: true},
+		{attempts: 3, wantDelay: 300 * time.Millisecond, wantRetry: true},
+		{attempts: 4, wantDelay: 0, wantRetry: false},
+	}
+	for _, tc := range tests {
+		delay, retry := p.Retry(ErrServiceUnavailable, tc.attempts, 0)
+		if delay != tc.wantDelay || retry != tc.wantRetry {
+			t.Errorf("attempt %v: expected (%v, %v), got (%v, %v)", tc.attempts, tc.wantDelay, tc.wantRetry, delay, retry)
+		}
+	}
+
+	if _, retry := p.Retry(ErrUnauthorized, 1, 0); retry {
+		t.Error("expected non-retryable error not to be retried")
+	}
+
+	p.MaxElapsedTime = time.Second
+	if _, retry := p.Retry(ErrServiceUnavailable, 1, 950*time.Millisecond); retry {
+		t.Error("expected no retry past MaxElapsedTime")
+	}
+
+	p.MaxElapsedTime = 0
+	if delay, _ := p.Retry(&TooManyRequestsError{RetryAfter: 2 * time.Second}, 1, 0); delay != 2*time.Second {
+		t.Errorf("expected Retry-After to win over a shorter backoff, got %v", delay)
+	}
+}
+
+func TestExponentialBackoffJitter(t *testing.T) {
+	p := &ExponentialBackoff{InitialInterval: 100 * time.Millisecond, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		delay, _ := p.Retry(ErrGatewayTimeout, 1, 0)
+		if delay < 50*time.Millisecond || delay > 150*time.Millisecond {
+			t.Fatalf("expected delay within jitter range, got %v", delay)
+		}
+	}
+}
+
+func TestDoAbortsRetryWhenContextIsDone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	c.SetRetryPolicy(&ExponentialBackoff{InitialInterval: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var v interface{}
+	t1 := time.Now()
+	err := c.do(ctx, QueryOperation, "{shop{name}}", nil, &v)
+	if !_errors.Is(err, context.DeadlineExceeded) || !_errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("expected deadline exceeded wrapping the last error, got %v", err)
+	}
+	if elapsed := time.Since(t1); elapsed > time.Second {
+		t.Errorf("expected sleep to be interrupted, waited %v", elapsed)
+	}
+}
+
+func TestNoRetry(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	c.SetRetries(3)
+	c.SetRetryPolicy(NoRetry)
+	var v interface{}
+	err := c.do(context.Background(), QueryOperation, "{shop{name}}", nil, &v)
+	if !_errors.Is(err, ErrGatewayTimeout) {
+		t.Errorf("expected ErrGatewayTimeout, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %v", calls)
+	}
+}
+
+func TestDoDoesNotRetryMutationWithUnknownOutcome(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	c.SetRetryPolicy(&ExponentialBackoff{MaxAttempts: 3, InitialInterval: time.Millisecond})
+	var v interface{}
+	err := c.do(context.Background(), MutationOperation, "mutation{productCreate{product{id}}}", nil, &v)
+	if !_errors.Is(err, ErrOutcomeUnknown) || !_errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("expected ErrOutcomeUnknown wrapping ErrServiceUnavailable, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %v", calls)
+	}
+
+	calls = 0
+	err = c.do(WithIdempotentMutation(context.Background()), MutationOperation, "mutation{productCreate{product{id}}}", nil, &v)
+	if !_errors.Is(err, ErrOutcomeUnknown) {
+		t.Errorf("expected ErrOutcomeUnknown, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected idempotent mutation to be retried, got %v calls", calls)
+	}
+}
+
+func TestDoRetriesMutationRejectedBeforeRunning(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data":{"productCreate":{"product":{"id":"gid://shopify/Product/1"}}}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, server.Client())
+	c.SetRetryPolicy(&ExponentialBackoff{MaxAttempts: 3, InitialInterval: time.Millisecond})
+	var v interface{}
+	err := c.do(context.Background(), MutationOperation, "mutation{productCreate{product{id}}}", nil, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %v", calls)
+	}
+}
+
+func TestDoRetriesMutationThatFailedToConnect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	c := NewClient(url, nil)
+	attempts := 0
+	c.SetRetryPolicy(&ExponentialBackoff{
+		MaxAttempts:     2,
+		InitialInterval: time.Millisecond,
+		Retryable: func(err error) bool {
+			attempts++
+			return true
+		},
+	})
+	var v interface{}
+	err := c.do(context.Background(), MutationOperation, "mutation{productCreate{product{id}}}", nil, &v)
+	if err == nil || _errors.Is(err, ErrOutcomeUnknown) {
+		t.Errorf("expected a connection error with a known outcome, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected the policy to be consulted once, got %v", attempts)
+	}
+}
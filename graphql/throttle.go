@@ -0,0 +1,202 @@
+package graphql
+
+import (
+	"container/list"
+	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// maxThrottleWaits bounds how many times a single operation waits for the cost bucket
+	// to refill after being throttled, so a misbehaving shop can't hold a caller forever.
+	maxThrottleWaits = 10
+	// maxCachedCosts bounds how many query costs a client remembers, since queries with inlined
+	// values are all different.
+	maxCachedCosts = 512
+	// bucketIdleTimeout is how long a shop's bucket is kept without being used. Shopify refills
+	// a bucket within seconds, so an idle bucket holds nothing worth keeping.
+	bucketIdleTimeout = 10 * time.Minute
+)
+
+// QueryCost represents the "extensions.cost" object Shopify returns with every response.
+//
+// Specification: https://shopify.dev/docs/api/usage/rate-limits#graphql-admin-api-rate-limits.
+type QueryCost struct {
+	RequestedQueryCost int            `json:"requestedQueryCost"`
+	ActualQueryCost    *int           `json:"actualQueryCost"`
+	ThrottleStatus     ThrottleStatus `json:"throttleStatus"`
+}
+
+// ThrottleStatus is the state of the shop's cost bucket after the query has run.
+type ThrottleStatus struct {
+	MaximumAvailable   float64 `json:"maximumAvailable"`
+	CurrentlyAvailable float64 `json:"currentlyAvailable"`
+	RestoreRate        float64 `json:"restoreRate"`
+}
+
+// costBucket is a leaky-bucket estimate of the points available to the app on a shop.
+// It is refreshed from every response and drained by every request, so callers can
+// wait just long enough before sending instead of being throttled by Shopify.
+type costBucket struct {
+	mu          sync.Mutex
+	known       bool
+	available   float64
+	maximum     float64
+	restoreRate float64
+	updatedAt   time.Time
+	lastUsed    time.Time
+}
+
+// buckets holds one costBucket per shop host, shared by all clients talking to that shop,
+// since Shopify applies the limit per app per shop regardless of the API version used.
+// Buckets unused for bucketIdleTimeout are dropped, so clients look them up for every operation.
+var (
+	buckets        sync.Map
+	bucketsSweptAt atomic.Int64
+)
+
+func bucketFor(host string) *costBucket {
+	now := time.Now()
+	sweepBuckets(now)
+	b, _ := buckets.LoadOrStore(host, &costBucket{})
+	bucket := b.(*costBucket)
+	bucket.mu.Lock()
+	bucket.lastUsed = now
+	bucket.mu.Unlock()
+	return bucket
+}
+
+// sweepBuckets drops the idle buckets, at most once per bucketIdleTimeout.
+func sweepBuckets(now time.Time) {
+	last := bucketsSweptAt.Load()
+	if now.Sub(time.Unix(0, last)) < bucketIdleTimeout || !bucketsSweptAt.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	buckets.Range(func(host, b any) bool {
+		bucket := b.(*costBucket)
+		bucket.mu.Lock()
+		idle := now.Sub(bucket.lastUsed) >= bucketIdleTimeout
+		bucket.mu.Unlock()
+		if idle {
+			buckets.Delete(host)
+		}
+		return true
+	})
+}
+
+// hostOf returns the host of rawURL, which is the shop domain for Shopify endpoints.
+func hostOf(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return rawURL
+}
+
+// update replaces the estimate with the throttle status reported by Shopify.
+func (b *costBucket) update(status ThrottleStatus) {
+	if status.MaximumAvailable <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.known = true
+	b.available = status.CurrentlyAvailable
+	b.maximum = status.MaximumAvailable
+	b.restoreRate = status.RestoreRate
+	b.updatedAt = time.Now()
+}
+
+// take reserves cost points and returns how long the caller must wait before the
+// reservation is covered. Reservations may drive the estimate negative so that
+// concurrent callers queue up behind each other.
+func (b *costBucket) take(cost float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.known || b.restoreRate <= 0 {
+		return 0
+	}
+	now := time.Now()
+	b.available += now.Sub(b.updatedAt).Seconds() * b.restoreRate
+	if b.available > b.maximum {
+		b.available = b.maximum
+	}
+	b.updatedAt = now
+	b.available -= cost
+	if b.available >= 0 {
+		return 0
+	}
+	return time.Duration(-b.available / b.restoreRate * float64(time.Second))
+}
+
+// wait blocks until the bucket is estimated to have room for cost, or ctx is done.
+func (b *costBucket) wait(ctx context.Context, cost float64) error {
+	return Sleep(ctx, b.take(cost))
+}
+
+// costCache is a least recently used cache of the costs Shopify requested for queries.
+// The zero value is ready to use.
+type costCache struct {
+	mu      sync.Mutex
+	order   *list.List // of *costEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type costEntry struct {
+	query string
+	cost  int
+}
+
+func (c *costCache) get(query string) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[query]
+	if !ok {
+		return 0, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*costEntry).cost, true
+}
+
+func (c *costCache) set(query string, cost int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+		c.order = list.New()
+	}
+	if e, ok := c.entries[query]; ok {
+		e.Value.(*costEntry).cost = cost
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[query] = c.order.PushFront(&costEntry{query: query, cost: cost})
+	if c.order.Len() > maxCachedCosts {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*costEntry).query)
+	}
+}
+
+func (c *costCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Sleep pauses for d, returning early with the context error if ctx is done first.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
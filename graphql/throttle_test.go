@@ -0,0 +1,51 @@
+package graphql
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCostCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	var c costCache
+	for i := 0; i < maxCachedCosts; i++ {
+		c.set(fmt.Sprintf("query q%d { shop { name } }", i), i)
+	}
+	// Using the oldest query keeps it over the next one.
+	if _, ok := c.get("query q0 { shop { name } }"); !ok {
+		t.Fatal("expected the cost of q0 to be cached")
+	}
+	c.set("query new { shop { name } }", 1)
+
+	if c.len() != maxCachedCosts {
+		t.Errorf("expected (%v), got (%v)", maxCachedCosts, c.len())
+	}
+	if _, ok := c.get("query q1 { shop { name } }"); ok {
+		t.Error("expected the least recently used cost to be evicted")
+	}
+	if cost, ok := c.get("query q0 { shop { name } }"); !ok || cost != 0 {
+		t.Errorf("expected (%v), got (%v)", 0, cost)
+	}
+}
+
+func TestSweepBucketsDropsIdleBuckets(t *testing.T) {
+	idle := bucketFor("idle.myshopify.com")
+	bucketFor("active.myshopify.com")
+	now := time.Now().Add(bucketIdleTimeout)
+	idle.mu.Lock()
+	idle.lastUsed = now.Add(-bucketIdleTimeout)
+	idle.mu.Unlock()
+	active, _ := buckets.Load("active.myshopify.com")
+	active.(*costBucket).mu.Lock()
+	active.(*costBucket).lastUsed = now.Add(-time.Second)
+	active.(*costBucket).mu.Unlock()
+
+	sweepBuckets(now)
+
+	if _, ok := buckets.Load("idle.myshopify.com"); ok {
+		t.Error("expected the idle bucket to be dropped")
+	}
+	if _, ok := buckets.Load("active.myshopify.com"); !ok {
+		t.Error("expected the active bucket to be kept")
+	}
+}
@@ -0,0 +1,69 @@
+package graphql
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Variable is a variable value with an explicit GraphQL type, see Var.
+type Variable struct {
+	Value interface{}
+	// Type is the GraphQL type of the variable as written in the operation, e.g. "String!" or "[ID!]".
+	Type string
+}
+
+// Var declares the GraphQL type of a variable explicitly, instead of deriving it from the Go type of value.
+// typ is written as is, so its nullability is independent of whether value is a pointer:
+// Var(title, "String") declares a nullable String, Var(title, "String!") a non-null one.
+func Var(value interface{}, typ string) Variable {
+	return Variable{Value: value, Type: typ}
+}
+
+// MarshalJSON sends the variable as its value.
+func (v Variable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Value)
+}
+
+var (
+	// variableTypes maps Go types to the GraphQL type of the variables holding them, see RegisterVariableType.
+	variableTypes sync.Map
+	// variableTypesGeneration is incremented by every RegisterVariableType call.
+	variableTypesGeneration atomic.Uint64
+)
+
+func init() {
+	// ID is an interface, so IDs passed as strings can't be told apart from strings.
+	// Shopify takes far more IDs than strings as arguments, so strings default to IDs.
+	// Declare strings with Var(value, "String!") or override this with RegisterVariableType.
+	RegisterVariableType("", "ID!")
+}
+
+// RegisterVariableType makes variables holding values of the Go type of sample declared as typ,
+// e.g. RegisterVariableType(null.String{}, "String"). A trailing "!" is dropped for pointers to that type.
+// It is meant to be called from init functions, and applies to all clients.
+func RegisterVariableType(sample interface{}, typ string) {
+	variableTypes.Store(reflect.TypeOf(sample), typ)
+	variableTypesGeneration.Add(1)
+	// The operations built so far may declare variables of that type differently.
+	queryCache.Range(func(key, _ interface{}) bool {
+		queryCache.Delete(key)
+		return true
+	})
+}
+
+// registeredVariableType returns the GraphQL type registered for t, if any.
+func registeredVariableType(t reflect.Type) (string, bool) {
+	typ, ok := variableTypes.Load(t)
+	if !ok {
+		return "", false
+	}
+	return typ.(string), true
+}
+
+// nullable strips the non-null marker of typ.
+func nullable(typ string) string {
+	return strings.TrimSuffix(typ, "!")
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+)
+
+// InMemory is a Recorder keeping everything it receives, e.g. to assert on metrics in tests.
+type InMemory struct {
+	mu             sync.Mutex
+	operations     []Operation
+	bulkOperations []BulkOperation
+}
+
+var _ Recorder = &InMemory{}
+
+// NewInMemory returns an empty InMemory recorder.
+func NewInMemory() *InMemory {
+	return &InMemory{}
+}
+
+func (m *InMemory) RecordOperation(_ context.Context, op Operation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.operations = append(m.operations, op)
+}
+
+func (m *InMemory) RecordBulkOperation(_ context.Context, op BulkOperation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bulkOperations = append(m.bulkOperations, op)
+}
+
+// Operations returns the operations recorded so far, oldest first.
+func (m *InMemory) Operations() []Operation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Operation(nil), m.operations...)
+}
+
+// BulkOperations returns the bulk operations recorded so far, oldest first.
+func (m *InMemory) BulkOperations() []BulkOperation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]BulkOperation(nil), m.bulkOperations...)
+}
+
+// Reset forgets everything recorded so far.
+func (m *InMemory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.operations = nil
+	m.bulkOperations = nil
+}
@@ -0,0 +1,54 @@
+// Package metrics abstracts the metrics backend used to report Shopify API calls,
+// so that clients can export them to Prometheus, StatsD or anything else.
+package metrics
+
+import (
+	"context"
+	"time"
+)
+
+// Operation describes a GraphQL operation sent by the library, including all of its attempts.
+type Operation struct {
+	ShopDomain string
+	// OperationName is the comma-separated list of the root fields selected by the operation, e.g. "products".
+	OperationName string
+	Mutation      bool
+	// Duration is the time taken by the operation, including retries and throttle waits.
+	Duration time.Duration
+	// StatusCode is the HTTP status of the last attempt, 0 if no response was received.
+	StatusCode int
+	// Retries is the number of attempts made after the first one because of errors.
+	Retries int
+	// ThrottleWaits is the number of times the operation waited for the cost bucket to refill after being throttled.
+	ThrottleWaits int
+	// RequestedQueryCost and ActualQueryCost come from the last response, 0 if Shopify didn't send them.
+	RequestedQueryCost int
+	ActualQueryCost    int
+	Err                error
+}
+
+// BulkOperation describes a bulk operation run by the library.
+type BulkOperation struct {
+	ShopDomain    string
+	OperationName string
+	// PollingDuration is the time spent waiting for Shopify to complete the operation.
+	PollingDuration time.Duration
+	// ResultSize is the size in bytes of the result file, 0 if there is none.
+	ResultSize int64
+	Err        error
+}
+
+// Recorder receives the metrics of the operations sent by the library. Implementations must be safe for concurrent use.
+type Recorder interface {
+	RecordOperation(ctx context.Context, op Operation)
+	RecordBulkOperation(ctx context.Context, op BulkOperation)
+}
+
+// Noop is a Recorder that records nothing.
+var Noop Recorder = noopRecorder{}
+
+type noopRecorder struct{}
+
+func (noopRecorder) RecordOperation(context.Context, Operation) {}
+
+func (noopRecorder) RecordBulkOperation(context.Context, BulkOperation) {}
@@ -1,5 +1,15 @@
 package shopify
 
+import (
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	graphqlclient "github.com/gempages/go-shopify-graphql/graph"
+	"github.com/gempages/go-shopify-graphql/graphql"
+	"github.com/gempages/go-shopify-graphql/tracer"
+)
+
 type (
 	QueryOption  func(builder QueryBuilder)
 	QueryBuilder interface {
@@ -33,3 +43,99 @@ func WithAfter(after string) QueryOption {
 		b.SetAfter(after)
 	}
 }
+
+// Option configures a Client created with New
+type Option func(o *clientOptions)
+
+type clientOptions struct {
+	api       API
+	version   APIVersion
+	graphOpts []graphqlclient.Option
+	logger    log.Ext1FieldLogger
+	err       error
+	// authenticated is set by the auth options.
+	authenticated bool
+}
+
+// WithAccessToken authenticates to the Admin API with an access token
+func WithAccessToken(token string) Option {
+	return func(o *clientOptions) {
+		o.api = AdminAPI
+		o.authenticated = true
+		o.graphOpts = append(o.graphOpts, graphqlclient.WithToken(token))
+	}
+}
+
+// WithPrivateAppAuth authenticates to the Admin API with the API key and password of a private app
+func WithPrivateAppAuth(apiKey string, password string) Option {
+	return func(o *clientOptions) {
+		o.api = AdminAPI
+		o.authenticated = true
+		o.graphOpts = append(o.graphOpts, graphqlclient.WithPrivateAppAuth(apiKey, password))
+	}
+}
+
+// WithStorefrontToken authenticates to the Storefront API with a public access token
+func WithStorefrontToken(token string) Option {
+	return func(o *clientOptions) {
+		o.api = StorefrontAPI
+		o.authenticated = true
+		o.graphOpts = append(o.graphOpts, graphqlclient.WithStoreFrontToken(token))
+	}
+}
+
+// WithStorefrontPrivateToken authenticates to the Storefront API with a private access token
+func WithStorefrontPrivateToken(token string) Option {
+	return func(o *clientOptions) {
+		o.api = StorefrontAPI
+		o.authenticated = true
+		o.graphOpts = append(o.graphOpts, graphqlclient.WithStoreFrontPrivateToken(token))
+	}
+}
+
+// WithAPIVersion pins the API version, e.g. APIVersion2024_10. New fails with ErrInvalidAPIVersion
+// if it isn't valid. Defaults to APIVersionDefault
+func WithAPIVersion(version APIVersion) Option {
+	return func(o *clientOptions) {
+		if err := version.Validate(); err != nil {
+			o.err = err
+			return
+		}
+		o.version = version
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *clientOptions) {
+		o.graphOpts = append(o.graphOpts, graphqlclient.WithHTTPClient(httpClient))
+	}
+}
+
+// WithRetryPolicy sets the policy deciding whether and when failed requests are retried. Defaults to no retries
+func WithRetryPolicy(policy graphql.RetryPolicy) Option {
+	return func(o *clientOptions) {
+		o.graphOpts = append(o.graphOpts, graphqlclient.WithRetryPolicy(policy))
+	}
+}
+
+// WithLogger sets the logger of the services. Defaults to the logrus standard logger
+func WithLogger(logger log.Ext1FieldLogger) Option {
+	return func(o *clientOptions) {
+		o.logger = logger
+	}
+}
+
+// WithTracer sets the tracer reporting requests. Defaults to Sentry
+func WithTracer(t tracer.Tracer) Option {
+	return func(o *clientOptions) {
+		o.graphOpts = append(o.graphOpts, graphqlclient.WithTracer(t))
+	}
+}
+
+// WithGraphQLOptions passes lower level options to the underlying GraphQL client, e.g. graphqlclient.WithTimeout
+func WithGraphQLOptions(opts ...graphqlclient.Option) Option {
+	return func(o *clientOptions) {
+		o.graphOpts = append(o.graphOpts, opts...)
+	}
+}
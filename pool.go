@@ -0,0 +1,245 @@
+package shopify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	graphqlclient "github.com/gempages/go-shopify-graphql/graph"
+	"github.com/gempages/go-shopify-graphql/graphql"
+)
+
+const (
+	defaultPoolIdleTimeout = 30 * time.Minute
+	defaultPoolCoolDown    = 10 * time.Minute
+)
+
+// ErrShopCoolingDown is returned for shops that recently answered with ErrLocked, ErrPaymentRequired
+// or ErrUnauthorized, until their cool-down period is over. The error also matches the original cause.
+var ErrShopCoolingDown = errors.New("shop is cooling down")
+
+// TokenStore provides the Admin API access token of a shop.
+type TokenStore interface {
+	Token(ctx context.Context, shopDomain string) (string, error)
+}
+
+// TokenStoreFunc adapts a function to a TokenStore.
+type TokenStoreFunc func(ctx context.Context, shopDomain string) (string, error)
+
+func (f TokenStoreFunc) Token(ctx context.Context, shopDomain string) (string, error) {
+	return f(ctx, shopDomain)
+}
+
+// PoolOption is used to configure a ClientPool
+type PoolOption func(p *ClientPool)
+
+// WithPoolIdleTimeout sets how long a shop's client is kept after its last use. Defaults to 30 minutes
+func WithPoolIdleTimeout(d time.Duration) PoolOption {
+	return func(p *ClientPool) {
+		p.idleTimeout = d
+	}
+}
+
+// WithPoolCoolDown sets how long a shop isn't called after it answered with ErrLocked, ErrPaymentRequired
+// or ErrUnauthorized. Defaults to 10 minutes
+func WithPoolCoolDown(d time.Duration) PoolOption {
+	return func(p *ClientPool) {
+		p.coolDown = d
+	}
+}
+
+// WithPoolClientOptions sets options applied to every client of the pool, after the token and the shared HTTP client.
+// Get fails with ErrInvalidAPIVersion if they set an invalid version with graphqlclient.WithVersion
+func WithPoolClientOptions(opts ...graphqlclient.Option) PoolOption {
+	return func(p *ClientPool) {
+		p.clientOpts = append(p.clientOpts, opts...)
+	}
+}
+
+// ClientPool lazily creates and caches one Client per shop, with access tokens taken from a TokenStore.
+// All clients share the same HTTP connections, and calls to the same shop share its throttle state.
+// It is safe for concurrent use.
+type ClientPool struct {
+	tokens      TokenStore
+	clientOpts  []graphqlclient.Option
+	httpClient  *http.Client
+	idleTimeout time.Duration
+	coolDown    time.Duration
+	now         func() time.Time
+
+	mu        sync.Mutex
+	shops     map[string]*pooledShop
+	lastSweep time.Time
+}
+
+type pooledShop struct {
+	client   *Client
+	lastUsed time.Time
+	// coolDownUntil and coolDownErr are set when the shop answered with an error worth backing off from.
+	coolDownUntil time.Time
+	coolDownErr   error
+}
+
+// NewClientPool returns a ClientPool getting access tokens from tokens.
+func NewClientPool(tokens TokenStore, opts ...PoolOption) *ClientPool {
+	p := &ClientPool{
+		tokens:      tokens,
+		httpClient:  &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+		idleTimeout: defaultPoolIdleTimeout,
+		coolDown:    defaultPoolCoolDown,
+		now:         time.Now,
+		shops:       make(map[string]*pooledShop),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Get returns the client of shopDomain, creating it with a token from the TokenStore if needed.
+// It fails with ErrShopCoolingDown while the shop is cooling down.
+func (p *ClientPool) Get(ctx context.Context, shopDomain string) (*Client, error) {
+	p.mu.Lock()
+	now := p.now()
+	p.evictIdleLocked(now)
+	shop := p.shops[shopDomain]
+	if shop != nil {
+		if err := shop.coolingDown(now); err != nil {
+			p.mu.Unlock()
+			return nil, err
+		}
+		if shop.client != nil {
+			shop.lastUsed = now
+			p.mu.Unlock()
+			return shop.client, nil
+		}
+	}
+	p.mu.Unlock()
+
+	version := APIVersion(graphqlclient.Version(append([]graphqlclient.Option{
+		graphqlclient.WithVersion(string(shopifyAPIVersion)),
+	}, p.clientOpts...)...))
+	if err := version.Validate(); err != nil {
+		return nil, err
+	}
+	token, err := p.tokens.Token(ctx, shopDomain)
+	if err != nil {
+		return nil, fmt.Errorf("get token of %s: %w", shopDomain, err)
+	}
+	opts := append([]graphqlclient.Option{
+		graphqlclient.WithVersion(string(shopifyAPIVersion)),
+		graphqlclient.WithToken(token),
+		graphqlclient.WithHTTPClient(p.httpClient),
+	}, p.clientOpts...)
+	client := NewClientWithOpts(shopDomain, opts...)
+	client.version = version
+	client.gql.Use(p.coolDownMiddleware(shopDomain))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	shop = p.shops[shopDomain]
+	if shop == nil {
+		shop = &pooledShop{}
+		p.shops[shopDomain] = shop
+	}
+	// Another caller may have created the client in the meantime, keep the first one.
+	if shop.client == nil {
+		shop.client = client
+	}
+	shop.lastUsed = p.now()
+	return shop.client, nil
+}
+
+// Remove forgets the client and cool-down of shopDomain, e.g. after the app was uninstalled or its token rotated.
+func (p *ClientPool) Remove(shopDomain string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.shops, shopDomain)
+}
+
+// Len returns the number of shops currently held by the pool, including the ones cooling down.
+func (p *ClientPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.shops)
+}
+
+// EvictIdle drops the clients that haven't been used for the idle timeout, and the cool-downs that are over.
+// It runs automatically from Get; calling it is only useful to release memory when Get isn't called.
+func (p *ClientPool) EvictIdle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastSweep = time.Time{}
+	p.evictIdleLocked(p.now())
+}
+
+// evictIdleLocked sweeps the shops at most twice per idle timeout. p.mu must be held.
+func (p *ClientPool) evictIdleLocked(now time.Time) {
+	if p.idleTimeout <= 0 || now.Sub(p.lastSweep) < p.idleTimeout/2 {
+		return
+	}
+	p.lastSweep = now
+	for domain, shop := range p.shops {
+		if now.Before(shop.coolDownUntil) {
+			continue
+		}
+		if now.Sub(shop.lastUsed) >= p.idleTimeout {
+			delete(p.shops, domain)
+		}
+	}
+}
+
+// coolDownMiddleware stops calls to shopDomain while it is cooling down,
+// and starts a cool-down when Shopify answers with ErrLocked, ErrPaymentRequired or ErrUnauthorized.
+func (p *ClientPool) coolDownMiddleware(shopDomain string) graphql.Middleware {
+	return func(next graphql.Handler) graphql.Handler {
+		return func(ctx context.Context, req *graphql.Request) (*graphql.Response, error) {
+			p.mu.Lock()
+			shop := p.shops[shopDomain]
+			var err error
+			if shop != nil {
+				err = shop.coolingDown(p.now())
+			}
+			p.mu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := next(ctx, req)
+			if errors.Is(err, graphql.ErrLocked) || errors.Is(err, graphql.ErrPaymentRequired) ||
+				errors.Is(err, graphql.ErrUnauthorized) {
+				p.startCoolDown(shopDomain, err)
+			}
+			return resp, err
+		}
+	}
+}
+
+func (p *ClientPool) startCoolDown(shopDomain string, cause error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	shop := p.shops[shopDomain]
+	if shop == nil {
+		shop = &pooledShop{}
+		p.shops[shopDomain] = shop
+	}
+	now := p.now()
+	shop.lastUsed = now
+	shop.coolDownUntil = now.Add(p.coolDown)
+	shop.coolDownErr = cause
+	// The token is likely revoked or rotated, get a fresh one once the cool-down is over.
+	if errors.Is(cause, graphql.ErrUnauthorized) {
+		shop.client = nil
+	}
+}
+
+// coolingDown returns an ErrShopCoolingDown error if the shop is cooling down at now.
+func (s *pooledShop) coolingDown(now time.Time) error {
+	if !now.Before(s.coolDownUntil) {
+		return nil
+	}
+	return fmt.Errorf("%w until %s: %w", ErrShopCoolingDown, s.coolDownUntil.Format(time.RFC3339), s.coolDownErr)
+}
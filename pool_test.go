@@ -0,0 +1,169 @@
+package shopify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	graphqlclient "github.com/gempages/go-shopify-graphql/graph"
+	"github.com/gempages/go-shopify-graphql/graphql"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newTestPool(status *atomic.Int32, tokenCalls *atomic.Int32, opts ...PoolOption) *ClientPool {
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: int(status.Load()),
+			Body:       io.NopCloser(strings.NewReader(`{"data":{"shop":{"name":"kyle"}}}`)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	})
+	tokens := TokenStoreFunc(func(ctx context.Context, shopDomain string) (string, error) {
+		tokenCalls.Add(1)
+		return "token-" + shopDomain, nil
+	})
+	opts = append(opts, WithPoolClientOptions(graphqlclient.WithBaseTransport(base)))
+	return NewClientPool(tokens, opts...)
+}
+
+func TestClientPoolReusesClients(t *testing.T) {
+	var status, tokenCalls atomic.Int32
+	status.Store(http.StatusOK)
+	p := newTestPool(&status, &tokenCalls)
+
+	c1, err := p.Get(context.Background(), "a.myshopify.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := p.Get(context.Background(), "a.myshopify.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c3, err := p.Get(context.Background(), "b.myshopify.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1 != c2 || c1 == c3 {
+		t.Errorf("expected one client per shop")
+	}
+	if tokenCalls.Load() != 2 {
+		t.Errorf("expected (2) token calls, got (%v)", tokenCalls.Load())
+	}
+	if p.Len() != 2 {
+		t.Errorf("expected (2) shops, got (%v)", p.Len())
+	}
+}
+
+func TestClientPoolValidatesAPIVersion(t *testing.T) {
+	tokens := TokenStoreFunc(func(ctx context.Context, shopDomain string) (string, error) {
+		return "token", nil
+	})
+	p := NewClientPool(tokens, WithPoolClientOptions(graphqlclient.WithVersion("2024-11")))
+	_, err := p.Get(context.Background(), "a.myshopify.com")
+	if !errors.Is(err, ErrInvalidAPIVersion) {
+		t.Errorf("expected (%v), got (%v)", ErrInvalidAPIVersion, err)
+	}
+
+	p = NewClientPool(tokens, WithPoolClientOptions(graphqlclient.WithVersion(string(APIVersion2024_10))))
+	c, err := p.Get(context.Background(), "a.myshopify.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.APIVersion() != APIVersion2024_10 {
+		t.Errorf("expected (%v), got (%v)", APIVersion2024_10, c.APIVersion())
+	}
+}
+
+func TestClientPoolEvictsIdleShops(t *testing.T) {
+	var status, tokenCalls atomic.Int32
+	status.Store(http.StatusOK)
+	now := time.Now()
+	p := newTestPool(&status, &tokenCalls, WithPoolIdleTimeout(time.Minute))
+	p.now = func() time.Time { return now }
+
+	_, err := p.Get(context.Background(), "a.myshopify.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Minute)
+	p.EvictIdle()
+	if p.Len() != 0 {
+		t.Errorf("expected idle shop to be evicted, got (%v) shops", p.Len())
+	}
+}
+
+func TestClientPoolCoolsDownLockedShops(t *testing.T) {
+	var status, tokenCalls atomic.Int32
+	status.Store(http.StatusLocked)
+	now := time.Now()
+	p := newTestPool(&status, &tokenCalls, WithPoolCoolDown(time.Minute))
+	p.now = func() time.Time { return now }
+
+	c, err := p.Get(context.Background(), "a.myshopify.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	err = c.GraphQLClient().QueryString(context.Background(), "{shop{name}}", nil, &v)
+	if !errors.Is(err, graphql.ErrLocked) {
+		t.Fatalf("expected (%v), got (%v)", graphql.ErrLocked, err)
+	}
+
+	status.Store(http.StatusOK)
+	_, err = p.Get(context.Background(), "a.myshopify.com")
+	if !IsShopCoolingDownError(err) || !IsLockedError(err) {
+		t.Errorf("expected cooling down error caused by locked shop, got (%v)", err)
+	}
+	err = c.GraphQLClient().QueryString(context.Background(), "{shop{name}}", nil, &v)
+	if !IsShopCoolingDownError(err) {
+		t.Errorf("expected calls through held clients to be refused, got (%v)", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	c2, err := p.Get(context.Background(), "a.myshopify.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c2 != c {
+		t.Errorf("expected the client to be kept after a lock")
+	}
+	err = c2.GraphQLClient().QueryString(context.Background(), "{shop{name}}", nil, &v)
+	if err != nil {
+		t.Errorf("expected no error after the cool-down, got (%v)", err)
+	}
+}
+
+func TestClientPoolRefreshesTokenAfterUnauthorized(t *testing.T) {
+	var status, tokenCalls atomic.Int32
+	status.Store(http.StatusUnauthorized)
+	now := time.Now()
+	p := newTestPool(&status, &tokenCalls, WithPoolCoolDown(time.Minute))
+	p.now = func() time.Time { return now }
+
+	c, err := p.Get(context.Background(), "a.myshopify.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	_ = c.GraphQLClient().QueryString(context.Background(), "{shop{name}}", nil, &v)
+
+	now = now.Add(2 * time.Minute)
+	c2, err := p.Get(context.Background(), "a.myshopify.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c2 == c || tokenCalls.Load() != 2 {
+		t.Errorf("expected a new client with a fresh token, got (%v) token calls", tokenCalls.Load())
+	}
+}
@@ -0,0 +1,62 @@
+package tracer
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// descriptionAttribute holds the span description, since OpenTelemetry spans only have a name.
+const descriptionAttribute = "shopify.description"
+
+type otelTracer struct {
+	tracer trace.Tracer
+}
+
+// NewOpenTelemetryTracer returns a Tracer creating spans with the given OpenTelemetry tracer,
+// e.g. otel.Tracer("github.com/gempages/go-shopify-graphql").
+func NewOpenTelemetryTracer(t trace.Tracer) Tracer {
+	return &otelTracer{tracer: t}
+}
+
+func (t *otelTracer) Start(ctx context.Context, operation string, description string) (context.Context, Span) {
+	ctx, span := t.tracer.Start(ctx, operation, trace.WithSpanKind(trace.SpanKindClient))
+	if description != "" {
+		span.SetAttributes(attribute.String(descriptionAttribute, description))
+	}
+	return ctx, &otelSpan{span: span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) SetAttribute(key string, value any) {
+	var kv attribute.KeyValue
+	switch v := value.(type) {
+	case string:
+		kv = attribute.String(key, v)
+	case int:
+		kv = attribute.Int(key, v)
+	case int64:
+		kv = attribute.Int64(key, v)
+	case float64:
+		kv = attribute.Float64(key, v)
+	case bool:
+		kv = attribute.Bool(key, v)
+	default:
+		kv = attribute.String(key, fmt.Sprint(v))
+	}
+	s.span.SetAttributes(kv)
+}
+
+func (s *otelSpan) Finish(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
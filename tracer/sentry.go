@@ -0,0 +1,34 @@
+package tracer
+
+import (
+	"context"
+
+	"github.com/gempages/go-helper/tracing"
+	"github.com/getsentry/sentry-go"
+)
+
+type sentryTracer struct{}
+
+// NewSentryTracer returns a Tracer reporting spans to the Sentry hub found in the context.
+// Attributes are stored in the span data.
+func NewSentryTracer() Tracer {
+	return sentryTracer{}
+}
+
+func (sentryTracer) Start(ctx context.Context, operation string, description string) (context.Context, Span) {
+	span := sentry.StartSpan(ctx, operation)
+	span.Description = description
+	return span.Context(), &sentrySpan{span: span}
+}
+
+type sentrySpan struct {
+	span *sentry.Span
+}
+
+func (s *sentrySpan) SetAttribute(key string, value any) {
+	s.span.SetData(key, value)
+}
+
+func (s *sentrySpan) Finish(err error) {
+	tracing.FinishSpan(s.span, err)
+}
@@ -0,0 +1,66 @@
+// Package tracer abstracts the tracing backend used to report Shopify API calls,
+// so that clients can use Sentry, OpenTelemetry or nothing at all.
+package tracer
+
+import (
+	"context"
+)
+
+// Attribute keys set on the spans started by this library.
+const (
+	AttributeOperationName      = "graphql.operation.name"
+	AttributeQuery              = "graphql.query"
+	AttributeVariables          = "graphql.variables"
+	AttributeShopDomain         = "shopify.shop_domain"
+	AttributeURL                = "url.full"
+	AttributeRequestedQueryCost = "shopify.query_cost.requested"
+	AttributeActualQueryCost    = "shopify.query_cost.actual"
+	AttributeRequestID          = "shopify.request_id"
+)
+
+// Tracer starts spans around Shopify API calls.
+type Tracer interface {
+	// Start starts a span named after the kind of operation, e.g. "shopify_graphql.send",
+	// with a description such as the GraphQL operation name. The returned context carries the span.
+	Start(ctx context.Context, operation string, description string) (context.Context, Span)
+}
+
+// Span is a unit of work started by a Tracer.
+type Span interface {
+	SetAttribute(key string, value any)
+	// Finish ends the span, marking it as failed if err is not nil.
+	Finish(err error)
+}
+
+// Noop is a Tracer that records nothing.
+var Noop Tracer = noopTracer{}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, any) {}
+
+func (noopSpan) Finish(error) {}
+
+// Default is the Tracer used when none is configured. It reports to Sentry, as this library always has.
+var Default Tracer = NewSentryTracer()
+
+type tracerKey struct{}
+
+// ContextWithTracer returns a copy of ctx carrying t, for code paths that only receive a context.
+func ContextWithTracer(ctx context.Context, t Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, t)
+}
+
+// FromContext returns the Tracer carried by ctx, or Default if there is none.
+func FromContext(ctx context.Context) Tracer {
+	if t, ok := ctx.Value(tracerKey{}).(Tracer); ok && t != nil {
+		return t
+	}
+	return Default
+}
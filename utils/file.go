@@ -10,9 +10,8 @@ import (
 	"os"
 	"time"
 
-	"github.com/gempages/go-helper/tracing"
 	pkghttp "github.com/gempages/go-shopify-graphql/http"
-	"github.com/getsentry/sentry-go"
+	"github.com/gempages/go-shopify-graphql/tracer"
 )
 
 func CloseFile(f *os.File) {
@@ -32,12 +31,10 @@ func ReadFile(file string) (data string, err error) {
 func DownloadFile(ctx context.Context, filepath string, url string) error {
 	var err error
 
-	span := sentry.StartSpan(ctx, "shopify.download_file")
-	span.Description = url
+	ctx, span := tracer.FromContext(ctx).Start(ctx, "shopify.download_file", url)
 	defer func() {
-		tracing.FinishSpan(span, err)
+		span.Finish(err)
 	}()
-	ctx = span.Context()
 
 	resp, err := httpGetWithRetry(url)
 	if err != nil {
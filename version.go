@@ -0,0 +1,156 @@
+package shopify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gempages/go-shopify-graphql-model/graph/model"
+
+	"github.com/gempages/go-shopify-graphql/graphql"
+)
+
+// APIVersion is a Shopify API version handle, e.g. "2024-10".
+//
+// Specification: https://shopify.dev/docs/api/usage/versioning.
+type APIVersion string
+
+const (
+	// APIVersionDefault lets Shopify pick the version, which is the oldest supported stable version
+	// unless another one is set for the app. Prefer pinning a version.
+	APIVersionDefault  APIVersion = "default"
+	APIVersionUnstable APIVersion = "unstable"
+
+	APIVersion2024_01 APIVersion = "2024-01"
+	APIVersion2024_04 APIVersion = "2024-04"
+	APIVersion2024_07 APIVersion = "2024-07"
+	APIVersion2024_10 APIVersion = "2024-10"
+	APIVersion2025_01 APIVersion = "2025-01"
+	APIVersion2025_04 APIVersion = "2025-04"
+	APIVersion2025_07 APIVersion = "2025-07"
+	APIVersion2025_10 APIVersion = "2025-10"
+	APIVersion2026_01 APIVersion = "2026-01"
+	APIVersion2026_04 APIVersion = "2026-04"
+	APIVersion2026_07 APIVersion = "2026-07"
+	APIVersion2026_10 APIVersion = "2026-10"
+)
+
+const apiVersionHeader = "X-Shopify-API-Version"
+
+var (
+	// ErrInvalidAPIVersion means an API version isn't a stable "YYYY-MM" release, "unstable" or "default".
+	ErrInvalidAPIVersion = errors.New("invalid API version")
+	// ErrAPIVersionUnsupported means Shopify doesn't support the API version the client uses anymore.
+	ErrAPIVersionUnsupported = errors.New("API version unsupported")
+	// ErrAPIVersionExpiring means the API version the client uses is in its last supported quarter.
+	ErrAPIVersionExpiring = errors.New("API version in its last supported quarter")
+)
+
+var apiVersionRegex = regexp.MustCompile(`^\d{4}-(01|04|07|10)$`)
+
+// Validate returns ErrInvalidAPIVersion if v isn't a stable release, APIVersionUnstable or APIVersionDefault.
+// New and ClientPool.Get validate the version their clients end up with, including one set
+// with graphqlclient.WithVersion. NewClientWithOpts and the other legacy constructors don't.
+func (v APIVersion) Validate() error {
+	if v == APIVersionDefault || v == APIVersionUnstable || apiVersionRegex.MatchString(string(v)) {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidAPIVersion, string(v))
+}
+
+// ReleasedAt returns the release date of a stable version, and false for other versions.
+func (v APIVersion) ReleasedAt() (time.Time, bool) {
+	if !apiVersionRegex.MatchString(string(v)) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("2006-01", string(v))
+	return t, err == nil
+}
+
+// PublicAPIVersions returns the API versions Shopify currently exposes, including unstable and release candidates.
+func (c *Client) PublicAPIVersions(ctx context.Context) ([]model.APIVersion, error) {
+	out, _, err := c.publicAPIVersions(ctx)
+	return out, err
+}
+
+var queryPublicAPIVersions = `
+query publicApiVersions {
+  publicApiVersions {
+	handle
+	displayName
+	supported
+  }
+}
+`
+
+// publicAPIVersions also returns the version Shopify served the query with.
+func (c *Client) publicAPIVersions(ctx context.Context) ([]model.APIVersion, APIVersion, error) {
+	out := struct {
+		PublicAPIVersions []model.APIVersion `json:"publicApiVersions"`
+	}{}
+	var md graphql.CallMetadata
+	err := c.gql.QueryString(graphql.WithCallMetadata(ctx, &md), queryPublicAPIVersions, nil, &out)
+	if err != nil {
+		return nil, "", fmt.Errorf("query: %w", err)
+	}
+	var served APIVersion
+	if md.Header != nil {
+		served = APIVersion(md.Header.Get(apiVersionHeader))
+	}
+	return out.PublicAPIVersions, served, nil
+}
+
+// CheckAPIVersion checks the API version the client uses against the versions Shopify supports.
+// It logs a warning and returns ErrAPIVersionUnsupported if the version isn't supported anymore,
+// or ErrAPIVersionExpiring if it is in its last supported quarter. With APIVersionDefault,
+// the version Shopify resolved it to is checked.
+func (c *Client) CheckAPIVersion(ctx context.Context) error {
+	versions, served, err := c.publicAPIVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("get public API versions: %w", err)
+	}
+	version := c.version
+	if version == APIVersionDefault || version == "" {
+		version = served
+	}
+	if version == "" || version == APIVersionUnstable {
+		return nil
+	}
+	return c.checkAPIVersion(version, versions, time.Now())
+}
+
+func (c *Client) checkAPIVersion(version APIVersion, versions []model.APIVersion, now time.Time) error {
+	supported := false
+	for _, v := range versions {
+		if v.Handle == string(version) {
+			supported = v.Supported
+			break
+		}
+	}
+	if !supported {
+		err := fmt.Errorf("%w: %s", ErrAPIVersionUnsupported, version)
+		c.log.Warnf("Shopify API version %s is not supported anymore, requests use the oldest supported version instead", version)
+		return err
+	}
+	releasedAt, ok := version.ReleasedAt()
+	if !ok {
+		return nil
+	}
+	endOfSupport := releasedAt.AddDate(1, 0, 0)
+	if now.After(endOfSupport.AddDate(0, -3, 0)) {
+		err := fmt.Errorf("%w: %s is supported until %s", ErrAPIVersionExpiring, version, endOfSupport.Format("2006-01"))
+		c.log.Warnf("Shopify API version %s is in its last supported quarter, it is supported until %s",
+			version, endOfSupport.Format("2006-01"))
+		return err
+	}
+	return nil
+}
+
+// ContextWithAPIVersion returns a context that makes the calls made with it use version instead of the client's,
+// e.g. to use a mutation only available in a newer version. The calls share the client's connections,
+// auth and rate-limit state. version should be valid, see APIVersion.Validate.
+func ContextWithAPIVersion(ctx context.Context, version APIVersion) context.Context {
+	return graphql.WithAPIVersion(ctx, string(version))
+}
@@ -0,0 +1,81 @@
+package shopify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gempages/go-shopify-graphql-model/graph/model"
+
+	graphqlclient "github.com/gempages/go-shopify-graphql/graph"
+)
+
+func TestNewValidatesAPIVersion(t *testing.T) {
+	_, err := New("example.myshopify.com", WithAccessToken("token"), WithAPIVersion("2024-11"))
+	if !errors.Is(err, ErrInvalidAPIVersion) {
+		t.Errorf("expected (%v), got (%v)", ErrInvalidAPIVersion, err)
+	}
+	_, err = New("example.myshopify.com", WithAccessToken("token"),
+		WithGraphQLOptions(graphqlclient.WithVersion("2024-11")))
+	if !errors.Is(err, ErrInvalidAPIVersion) {
+		t.Errorf("expected (%v), got (%v)", ErrInvalidAPIVersion, err)
+	}
+
+	var got *http.Request
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"data":{"publicApiVersions":[{"handle":"2024-10","supported":true}]}}`)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	})
+	c, err := New("example.myshopify.com", WithAccessToken("token"), WithAPIVersion(APIVersion2024_10),
+		WithGraphQLOptions(graphqlclient.WithBaseTransport(base)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := c.PublicAPIVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].Handle != "2024-10" {
+		t.Errorf("unexpected versions %+v", versions)
+	}
+	if got.URL.Path != "/admin/api/2024-10/graphql.json" {
+		t.Errorf("expected (/admin/api/2024-10/graphql.json), got (%v)", got.URL.Path)
+	}
+}
+
+func TestCheckAPIVersion(t *testing.T) {
+	versions := []model.APIVersion{
+		{Handle: "2024-04", Supported: false},
+		{Handle: "2024-07", Supported: true},
+		{Handle: "2024-10", Supported: true},
+		{Handle: "unstable", Supported: false},
+	}
+	now := time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		version APIVersion
+		want    error
+	}{
+		{version: APIVersion2024_04, want: ErrAPIVersionUnsupported},
+		{version: APIVersion2024_07, want: ErrAPIVersionExpiring},
+		{version: APIVersion2024_10, want: nil},
+	}
+	c, err := New("example.myshopify.com", WithAccessToken("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		err := c.checkAPIVersion(tt.version, versions, now)
+		if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("%s: expected (%v), got (%v)", tt.version, tt.want, err)
+		}
+	}
+}
@@ -86,7 +86,7 @@ func (w WebhookServiceOp) NewWebhookSubscription(ctx context.Context, topic mode
 	}
 
 	if len(v.WebhookCreateResult.UserErrors) > 0 {
-		err = fmt.Errorf("%+v", v.WebhookCreateResult.UserErrors)
+		err = modelUserErrors(v.WebhookCreateResult.UserErrors)
 		return
 	}
 
@@ -110,7 +110,7 @@ func (w WebhookServiceOp) NewEventBridgeWebhookSubscription(ctx context.Context,
 	}
 
 	if len(v.EventBridgeWebhookCreateResult.UserErrors) > 0 {
-		err = fmt.Errorf("%+v", v.EventBridgeWebhookCreateResult.UserErrors)
+		err = modelUserErrors(v.EventBridgeWebhookCreateResult.UserErrors)
 		return
 	}
 
@@ -128,7 +128,7 @@ func (w WebhookServiceOp) DeleteWebhook(ctx context.Context, webhookID string) (
 	}
 
 	if len(m.WebhookDeleteResult.UserErrors) > 0 {
-		err = fmt.Errorf("%+v", m.WebhookDeleteResult.UserErrors)
+		err = modelUserErrors(m.WebhookDeleteResult.UserErrors)
 		return
 	}
 	return m.WebhookDeleteResult.DeletedWebhookSubscriptionID, nil
@@ -220,7 +220,7 @@ func (w WebhookServiceOp) UpdateWebhookSubscription(ctx context.Context, webhook
 	}
 
 	if len(v.WebhookUpdateResult.UserErrors) > 0 {
-		err = fmt.Errorf("%+v", v.WebhookUpdateResult.UserErrors)
+		err = modelUserErrors(v.WebhookUpdateResult.UserErrors)
 		return
 	}
 